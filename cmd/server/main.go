@@ -2,18 +2,29 @@ package main
 
 import (
 	"app/internal/application"
+	"app/internal/config"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 )
 
 func main() {
 	// app
-	// - config
-	token := os.Getenv("API_TOKEN")
-	app := application.NewDefaultHTTP(":8080", token)
+	// - config: defaults < config file < environment < flags
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	app := application.NewDefaultHTTP(cfg.Application())
 	// - run
 	if err := app.Run(); err != nil {
 		fmt.Println(err)
-		return
+		os.Exit(1)
 	}
 }
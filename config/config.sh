@@ -1 +1,5 @@
-export API_TOKEN="123456"
\ No newline at end of file
+# export PRODUCTS_CONFIG="config/config.yaml"
+export API_TOKEN="123456"
+export PRODUCTS_FILE="products.json"
+export API_KEYS_FILE="config/api_keys.example.json"
+export HMAC_KEYS_FILE="config/hmac_keys.example.json"
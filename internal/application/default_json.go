@@ -1,24 +1,121 @@
 package application
 
 import (
-	"app/internal"
 	"app/internal/handler"
+	"app/internal/metrics"
 	"app/internal/middleware"
 	"app/internal/repository"
 	"app/internal/service"
+	"app/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
-// NewDefaultHTTP creates a new instance of a default http server
-func NewDefaultHTTP(addr string, token string) *DefaultHTTP {
-	// default config / values
-	// ...
+// ConfigDefaultHTTP is a struct that represents the configuration of the default http server
+type ConfigDefaultHTTP struct {
+	// Addr is the address of the http server
+	Addr string
+	// ReadTimeout is the maximum duration to read a request, including its body
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout is the maximum duration to read the headers of a request
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout is the maximum duration to write a response
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum duration a keep-alive connection waits for the next request
+	IdleTimeout time.Duration
+	// ShutdownTimeout is the maximum duration to drain the connections on SIGINT / SIGTERM
+	ShutdownTimeout time.Duration
+	// Token is the token of the http server (used when there is no KeysFile)
+	Token string
+	// AllowEmptyToken disables the authentication when there is no Token nor KeysFile (never in production)
+	AllowEmptyToken bool
+	// KeysFile is the path of the json file with the scoped api keys
+	KeysFile string
+	// JWT is the configuration of the JWT authentication, accepted besides the token / api keys
+	// - it is enabled by a secret (HS256) or a JWKS file (RS256 / ES256)
+	JWT middleware.ConfigJWT
+	// HMACKeysFile is the path of the json file with the keys of the signed (HMAC) requests
+	HMACKeysFile string
+	// HMACWindow is the maximum clock difference of a signed request
+	HMACWindow time.Duration
+	// HMACGroups are the route groups accepting signed requests: "write" and / or "delete"
+	HMACGroups []string
+	// CORS is the configuration of the cross-origin requests (disabled without allowed origins)
+	CORS middleware.ConfigCORS
+	// RateLimits are the limits of the requests of each client, by route group ("read", "write", "delete", "auth")
+	// or by route ("GET /products"), the route taking precedence over its group
+	// - a group without a limit is not limited
+	RateLimits map[string]middleware.RateLimit
+	// RateLimitTrustProxy identifies the clients without authentication by X-Forwarded-For instead of the remote address
+	RateLimitTrustProxy bool
+	// FilePath is the path of the json file where the products are stored
+	FilePath string
+	// StorageBackend is the storage of the products: "json" (rewrites the file) or "journal" (appends every change)
+	StorageBackend string
+	// CompactEvery is the number of journal records after which the journal is compacted (0 disables it)
+	CompactEvery int
+	// LogLevel is the minimum level of the access log: debug, info, warn or error
+	LogLevel string
+	// LogFormat is the format of the access log: json or text
+	LogFormat string
+	// LogOutput is the sink of the logs: stdout, stderr or the path of a file
+	LogOutput string
+	// LogSkipPaths are the paths left out of the access log (e.g. the health probes and the metrics)
+	LogSkipPaths []string
+}
+
+// route groups of the endpoints
+const (
+	// GroupRead are the endpoints getting products (without authentication)
+	GroupRead = "read"
+	// GroupWrite are the endpoints creating and updating products
+	GroupWrite = "write"
+	// GroupDelete are the endpoints deleting products
+	GroupDelete = "delete"
+	// GroupAuth are the endpoints with authentication, limited by ip before the authentication
+	// - so the failed attempts (e.g. guessing a token) are limited too
+	GroupAuth = "auth"
+)
 
+// NewDefaultHTTP creates a new instance of a default http server
+// - cfg is used as is: the defaults are the ones of config.Default, and config.Load validates them
+func NewDefaultHTTP(cfg ConfigDefaultHTTP) *DefaultHTTP {
 	return &DefaultHTTP{
-		addr:  addr,
-		token: token,
+		addr: cfg.Addr,
+		timeouts: timeouts{
+			read:       cfg.ReadTimeout,
+			readHeader: cfg.ReadHeaderTimeout,
+			write:      cfg.WriteTimeout,
+			idle:       cfg.IdleTimeout,
+			shutdown:   cfg.ShutdownTimeout,
+		},
+		token:          cfg.Token,
+		allowEmpty:     cfg.AllowEmptyToken,
+		keysFile:       cfg.KeysFile,
+		jwt:            cfg.JWT,
+		hmacKeysFile:   cfg.HMACKeysFile,
+		hmacWindow:     cfg.HMACWindow,
+		hmacGroups:     cfg.HMACGroups,
+		cors:           cfg.CORS,
+		rateLimits:     cfg.RateLimits,
+		trustProxy:     cfg.RateLimitTrustProxy,
+		filePath:       cfg.FilePath,
+		storageBackend: cfg.StorageBackend,
+		compactEvery:   cfg.CompactEvery,
+		logLevel:       cfg.LogLevel,
+		logFormat:      cfg.LogFormat,
+		logOutput:      cfg.LogOutput,
+		logSkipPaths:   cfg.LogSkipPaths,
 	}
 }
 
@@ -26,44 +123,262 @@ func NewDefaultHTTP(addr string, token string) *DefaultHTTP {
 type DefaultHTTP struct {
 	// addr is the address of the http server
 	addr string
+	// timeouts are the timeouts of the http server
+	timeouts timeouts
 	// token is the token of the http server
 	token string
+	// allowEmpty disables the authentication when there is no token nor keys file
+	allowEmpty bool
+	// keysFile is the path of the json file with the scoped api keys
+	keysFile string
+	// jwt is the configuration of the JWT authentication
+	jwt middleware.ConfigJWT
+	// hmacKeysFile is the path of the json file with the keys of the signed requests
+	hmacKeysFile string
+	// hmacWindow is the maximum clock difference of a signed request
+	hmacWindow time.Duration
+	// hmacGroups are the route groups accepting signed requests
+	hmacGroups []string
+	// cors is the configuration of the cross-origin requests
+	cors middleware.ConfigCORS
+	// rateLimits are the limits of the requests of each client by route group or route
+	rateLimits map[string]middleware.RateLimit
+	// trustProxy identifies the clients without authentication by X-Forwarded-For
+	trustProxy bool
+	// filePath is the path of the json file where the products are stored
+	filePath string
+	// storageBackend is the storage of the products
+	storageBackend string
+	// compactEvery is the number of journal records after which the journal is compacted
+	compactEvery int
+	// logLevel is the minimum level of the access log
+	logLevel string
+	// logFormat is the format of the access log
+	logFormat string
+	// logOutput is the sink of the logs
+	logOutput string
+	// logSkipPaths are the paths left out of the access log
+	logSkipPaths []string
+}
+
+// timeouts is a struct that represents the timeouts of the http server
+type timeouts struct {
+	read       time.Duration
+	readHeader time.Duration
+	write      time.Duration
+	idle       time.Duration
+	shutdown   time.Duration
 }
 
-// Run runs the http server
+// Run runs the http server until SIGINT / SIGTERM
+//   - on a signal, it stops accepting connections, drains the in-flight requests within the shutdown timeout,
+//     and flushes the products to the storage
 func (h *DefaultHTTP) Run() (err error) {
+	start := time.Now()
+
 	// initialize dependencies
-	// - db
-	db := internal.LoadProducts()
+	// - storage
+	var st storage.ProductStorageJSON
+	switch h.storageBackend {
+	case "json":
+		st = storage.NewStorageProductJSON(h.filePath, true)
+	case "journal":
+		st = storage.NewStorageProductJournal(h.filePath, h.compactEvery)
+	default:
+		err = fmt.Errorf("unknown storage backend %q", h.storageBackend)
+		return
+	}
 	// - repository
-	rp := repository.NewProductsMap(db)
+	rp, err := repository.NewProductsStorage(st)
+	if err != nil {
+		err = fmt.Errorf("cannot load the products: %w", err)
+		return
+	}
+	defer func() {
+		// final flush, once no request can change the products
+		if errClose := rp.Close(); errClose != nil {
+			err = errors.Join(err, fmt.Errorf("cannot flush the products: %w", errClose))
+		}
+	}()
 	// - service
 	sv := service.NewProductDefault(rp)
 	// - handler
 	hd := handler.NewDefaultProducts(sv)
+	hh := handler.NewDefaultHealth(rp, start)
+	// - metrics: of the http requests and of the inventory
+	reg := metrics.NewRegistry()
+	requestMetrics := middleware.NewMetrics(reg)
+	reg.NewGaugeFuncs(func() []metrics.Gauge {
+		stats := sv.Stats(context.Background(), 7)
+		return []metrics.Gauge{
+			{Name: "products", Help: "Number of products.", Value: float64(stats.Count)},
+			{Name: "products_published", Help: "Number of published products.", Value: float64(stats.Published)},
+			{Name: "products_stock", Help: "Sum of the quantities of the products.", Value: float64(stats.Stock)},
+			{Name: "products_value", Help: "Sum of the price of the products by their quantity.", Value: stats.Value.Float64()},
+			{Name: "products_expiring_7d", Help: "Number of products expiring within 7 days.", Value: float64(stats.Expiring)},
+		}
+	})
 	// - router
 	rt := chi.NewRouter()
 	// - middleware
-	auth := middleware.NewAuthenticator(h.token)
-	logger := middleware.NewLogger()
+	// - authentication: scoped api keys, or the shared token if there is no keys file
+	//   and signed JWTs when they are enabled
+	var authenticate func(scopes ...string) func(http.Handler) http.Handler
+	if h.keysFile != "" {
+		keys, errKeys := middleware.LoadAPIKeys(h.keysFile)
+		if errKeys != nil {
+			err = errKeys
+			return
+		}
+		authenticate = keys.RequireScopes
+	} else {
+		auth, errAuth := middleware.NewAuthenticator(h.token, h.allowEmpty)
+		if errAuth != nil {
+			err = errAuth
+			return
+		}
+		authenticate = func(scopes ...string) func(http.Handler) http.Handler {
+			return auth.ValidateToken
+		}
+	}
+	if h.jwt.Secret != "" || h.jwt.JWKSFile != "" {
+		jwt, errJWT := middleware.NewJWT(h.jwt)
+		if errJWT != nil {
+			err = errJWT
+			return
+		}
+		fallback := authenticate
+		authenticate = func(scopes ...string) func(http.Handler) http.Handler {
+			return jwt.Authenticate(fallback(scopes...), scopes...)
+		}
+	}
+	// - signed requests (machine to machine), only in the selected route groups
+	authenticateGroup := func(group string, scopes ...string) func(http.Handler) http.Handler {
+		return authenticate(scopes...)
+	}
+	if h.hmacKeysFile != "" {
+		for _, group := range h.hmacGroups {
+			if group != GroupWrite && group != GroupDelete {
+				err = fmt.Errorf("unknown hmac route group %q", group)
+				return
+			}
+		}
+		hmacKeys, errKeys := middleware.LoadHMACKeys(h.hmacKeysFile)
+		if errKeys != nil {
+			err = errKeys
+			return
+		}
+		signed, errHMAC := middleware.NewHMAC(middleware.ConfigHMAC{Keys: hmacKeys, Window: h.hmacWindow})
+		if errHMAC != nil {
+			err = errHMAC
+			return
+		}
+		authenticateGroup = func(group string, scopes ...string) func(http.Handler) http.Handler {
+			if !slices.Contains(h.hmacGroups, group) {
+				return authenticate(scopes...)
+			}
+			return signed.Authenticate(authenticate(scopes...), scopes...)
+		}
+	}
+	// - rate limit, by route or, without a limit for the route, by route group
+	limiter := middleware.NewRateLimiter(middleware.ConfigRateLimiter{TrustProxy: h.trustProxy})
+	limit := func(group, method, pattern string) func(http.Handler) http.Handler {
+		route := method + " " + pattern
+		if l, ok := h.rateLimits[route]; ok {
+			return limiter.Limit(route, l)
+		}
+		return limiter.Limit(group, h.rateLimits[group])
+	}
+	var logLevel slog.Level
+	if err = logLevel.UnmarshalText([]byte(h.logLevel)); err != nil {
+		err = fmt.Errorf("invalid log level %q: %w", h.logLevel, err)
+		return
+	}
+	logOutput, err := middleware.OpenLogOutput(h.logOutput)
+	if err != nil {
+		return
+	}
+	defer logOutput.Close()
+	logCfg := middleware.ConfigLogger{
+		Output:    logOutput,
+		Format:    h.logFormat,
+		Level:     logLevel,
+		SkipPaths: h.logSkipPaths,
+	}
+	slog.SetDefault(middleware.NewSlogLogger(logCfg))
+	logger := middleware.NewLogger(logCfg)
+	requestID := middleware.NewRequestID()
 
 	// endpoints
+	rt.Use(requestID.Handle)
 	rt.Use(logger.Log)
+	rt.Use(requestMetrics.Observe)
+	if len(h.cors.AllowedOrigins) > 0 {
+		rt.Use(middleware.NewCORS(h.cors).Handle)
+	}
+
+	// probes of the orchestrator and metrics, without authentication nor rate limit
+	rt.Get("/healthz", hh.Healthz())
+	rt.Get("/readyz", hh.Readyz())
+	rt.Get("/version", hh.Version())
+	rt.Get("/metrics", reg.Handler())
 
 	// get routes without authentication
-	rt.Get("/products", hd.GetAll())
-	rt.Get("/products/{id}", hd.GetById())
+	rt.With(limit(GroupRead, "GET", "/products")).Get("/products", hd.GetAll())
+	rt.With(limit(GroupRead, "GET", "/products/search")).Get("/products/search", hd.Search())
+	rt.With(limit(GroupRead, "GET", "/products/{id}")).Get("/products/{id}", hd.GetById())
+	rt.With(limit(GroupRead, "GET", "/products/code/{code}")).Get("/products/code/{code}", hd.GetByCode())
 
 	// rutes with authentication
+	// - the limit of the ip goes before the authentication, so the requests failing it are limited too
+	// - the limit of the route goes after the authentication, so the clients are identified by their principal
+	limitAuth := limiter.Limit(GroupAuth, h.rateLimits[GroupAuth])
+	rt.Group(func(r chi.Router) {
+		r.Use(limitAuth)
+		r.Use(authenticateGroup(GroupWrite, middleware.ScopeProductsWrite))
+		r.With(limit(GroupWrite, "POST", "/products")).Post("/products", hd.Create())
+		r.With(limit(GroupWrite, "PUT", "/products/{id}")).Put("/products/{id}", hd.Update())
+		r.With(limit(GroupWrite, "PATCH", "/products/{id}")).Patch("/products/{id}", hd.UpdatePartial())
+	})
 	rt.Group(func(r chi.Router) {
-		r.Use(auth.ValidateToken)
-		r.Post("/products", hd.Create())
-		r.Put("/products/{id}", hd.Update())
-		r.Patch("/products/{id}", hd.UpdatePartial())
-		r.Delete("/products/{id}", hd.Delete())
+		r.Use(limitAuth)
+		r.Use(authenticateGroup(GroupDelete, middleware.ScopeProductsDelete))
+		r.With(limit(GroupDelete, "DELETE", "/products/{id}")).Delete("/products/{id}", hd.Delete())
 	})
 
 	// run http server
-	err = http.ListenAndServe(h.addr, rt)
+	srv := &http.Server{
+		Addr:              h.addr,
+		Handler:           rt,
+		ReadTimeout:       h.timeouts.read,
+		ReadHeaderTimeout: h.timeouts.readHeader,
+		WriteTimeout:      h.timeouts.write,
+		IdleTimeout:       h.timeouts.idle,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serveErr:
+		// the server could not start (e.g. the address is in use)
+		return
+	case <-ctx.Done():
+		// a second signal kills the process without waiting
+		stop()
+	}
+
+	// graceful shutdown
+	slog.Info("shutting down", slog.Duration("timeout", h.timeouts.shutdown))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), h.timeouts.shutdown)
+	defer cancel()
+	if err = srv.Shutdown(shutdownCtx); err != nil {
+		err = fmt.Errorf("cannot drain the connections: %w", err)
+		return
+	}
 	return
 }
@@ -0,0 +1,482 @@
+package config
+
+import (
+	"app/internal/application"
+	"app/internal/middleware"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// in this file i handle the configuration of the server, merged by layers:
+// defaults < config file (yaml or json) < environment variables < command line flags
+
+// errors
+var (
+	ErrConfigFile    = errors.New("invalid config file")
+	ErrConfigInvalid = errors.New("invalid config")
+)
+
+// EnvConfigFile is the environment variable with the path of the config file (also the flag -config)
+const EnvConfigFile = "PRODUCTS_CONFIG"
+
+// Config is a struct that represents the configuration of the server
+type Config struct {
+	// Server is the configuration of the http server
+	Server Server `yaml:"server"`
+	// Storage is the configuration of the storage of the products
+	Storage Storage `yaml:"storage"`
+	// Auth is the configuration of the authentication
+	Auth Auth `yaml:"auth"`
+	// Log is the configuration of the logs
+	Log Log `yaml:"log"`
+	// CORS is the configuration of the cross-origin requests
+	CORS CORS `yaml:"cors"`
+	// RateLimit is the configuration of the rate limit
+	RateLimit RateLimit `yaml:"rate_limit"`
+}
+
+// Server is a struct that represents the configuration of the http server
+type Server struct {
+	Addr              string        `yaml:"addr"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Storage is a struct that represents the configuration of the storage of the products
+type Storage struct {
+	// Path is the path of the json file with the products
+	Path string `yaml:"path"`
+	// Backend is the storage: "json" or "journal"
+	Backend string `yaml:"backend"`
+	// CompactEvery is the number of journal records after which the journal is compacted
+	CompactEvery int `yaml:"compact_every"`
+}
+
+// Auth is a struct that represents the configuration of the authentication
+type Auth struct {
+	// Token is the shared token, used without KeysFile (better set by the environment)
+	Token string `yaml:"token"`
+	// AllowEmptyToken disables the authentication without Token nor KeysFile (never in production)
+	AllowEmptyToken bool `yaml:"allow_empty_token"`
+	// KeysFile is the path of the json file with the scoped api keys
+	KeysFile string `yaml:"keys_file"`
+	// JWT is the configuration of the JWT authentication
+	JWT JWT `yaml:"jwt"`
+	// HMAC is the configuration of the signed requests
+	HMAC HMAC `yaml:"hmac"`
+}
+
+// JWT is a struct that represents the configuration of the JWT authentication
+type JWT struct {
+	Secret   string        `yaml:"secret"`
+	JWKSFile string        `yaml:"jwks_file"`
+	Issuer   string        `yaml:"issuer"`
+	Audience string        `yaml:"audience"`
+	Leeway   time.Duration `yaml:"leeway"`
+}
+
+// HMAC is a struct that represents the configuration of the signed requests
+type HMAC struct {
+	KeysFile string        `yaml:"keys_file"`
+	Window   time.Duration `yaml:"window"`
+	Groups   []string      `yaml:"groups"`
+}
+
+// Log is a struct that represents the configuration of the logs
+type Log struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+	// Output is the sink of the logs: stdout, stderr or the path of a file
+	Output    string   `yaml:"output"`
+	SkipPaths []string `yaml:"skip_paths"`
+}
+
+// CORS is a struct that represents the configuration of the cross-origin requests
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+// RateLimit is a struct that represents the configuration of the rate limit
+type RateLimit struct {
+	// Limits are the limits by route group or route, written as "requests/period[:burst]" (e.g. "60/1m")
+	// - the limits of a layer replace the ones of the previous layer as a whole, so a group left out has no limit
+	Limits map[string]string `yaml:"limits"`
+	// TrustProxy identifies the clients without authentication by X-Forwarded-For
+	TrustProxy bool `yaml:"trust_proxy"`
+}
+
+// Default returns the default configuration
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:              ":8080",
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		Storage: Storage{
+			Path:         "products.json",
+			Backend:      "json",
+			CompactEvery: 1000,
+		},
+		Auth: Auth{
+			JWT:  JWT{Leeway: 30 * time.Second},
+			HMAC: HMAC{Window: 5 * time.Minute, Groups: []string{application.GroupWrite}},
+		},
+		Log: Log{
+			Level:     "info",
+			Format:    "json",
+			Output:    "stdout",
+			SkipPaths: []string{"/healthz", "/readyz", "/metrics"},
+		},
+		CORS: CORS{
+			ExposedHeaders: []string{"ETag", middleware.HeaderRequestID, middleware.HeaderRateLimitLimit, middleware.HeaderRateLimitRemaining, middleware.HeaderRateLimitReset, middleware.HeaderRetryAfter},
+			MaxAge:         10 * time.Minute,
+		},
+		RateLimit: RateLimit{
+			Limits: map[string]string{
+				application.GroupRead:   "600/1m",
+				application.GroupWrite:  "60/1m",
+				application.GroupDelete: "60/1m",
+				application.GroupAuth:   "120/1m",
+			},
+		},
+	}
+}
+
+// setting is a value of the configuration that can be set by the environment and / or a flag
+type setting struct {
+	// flag is the name of the flag (empty for the secrets, which are not set by flags)
+	flag string
+	// env is the name of the environment variable
+	env string
+	// usage is the description of the flag
+	usage string
+	// isBool marks the flags without value
+	isBool bool
+	// set parses a value into the configuration
+	set func(cfg *Config, value string) error
+}
+
+// settings are the values of the configuration that can be set by the environment and flags
+var settings = []setting{
+	{flag: "addr", env: "PRODUCTS_ADDR", usage: "address of the http server", set: setString(func(c *Config) *string { return &c.Server.Addr })},
+	{flag: "read-timeout", env: "PRODUCTS_READ_TIMEOUT", usage: "maximum duration to read a request, including its body", set: setDuration(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{flag: "read-header-timeout", env: "PRODUCTS_READ_HEADER_TIMEOUT", usage: "maximum duration to read the headers of a request", set: setDuration(func(c *Config) *time.Duration { return &c.Server.ReadHeaderTimeout })},
+	{flag: "write-timeout", env: "PRODUCTS_WRITE_TIMEOUT", usage: "maximum duration to write a response", set: setDuration(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{flag: "idle-timeout", env: "PRODUCTS_IDLE_TIMEOUT", usage: "maximum duration a keep-alive connection waits for the next request", set: setDuration(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{flag: "shutdown-timeout", env: "PRODUCTS_SHUTDOWN_TIMEOUT", usage: "maximum duration to drain the connections on SIGINT / SIGTERM", set: setDuration(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{flag: "data", env: "PRODUCTS_FILE", usage: "path of the json file with the products", set: setString(func(c *Config) *string { return &c.Storage.Path })},
+	{flag: "storage", env: "PRODUCTS_STORAGE", usage: "storage of the products: json or journal", set: setString(func(c *Config) *string { return &c.Storage.Backend })},
+	{flag: "compact-every", env: "PRODUCTS_COMPACT_EVERY", usage: "number of journal records after which the journal is compacted", set: setInt(func(c *Config) *int { return &c.Storage.CompactEvery })},
+	{env: "API_TOKEN", set: setString(func(c *Config) *string { return &c.Auth.Token })},
+	{flag: "allow-empty-token", env: "PRODUCTS_ALLOW_EMPTY_TOKEN", usage: "disable the authentication without token nor keys file (never in production)", isBool: true, set: setBool(func(c *Config) *bool { return &c.Auth.AllowEmptyToken })},
+	{flag: "keys", env: "API_KEYS_FILE", usage: "path of the json file with the scoped api keys", set: setString(func(c *Config) *string { return &c.Auth.KeysFile })},
+	{env: "JWT_SECRET", set: setString(func(c *Config) *string { return &c.Auth.JWT.Secret })},
+	{flag: "jwks", env: "JWT_JWKS_FILE", usage: "path of the JWKS file with the RS256 / ES256 keys of the tokens", set: setString(func(c *Config) *string { return &c.Auth.JWT.JWKSFile })},
+	{flag: "jwt-issuer", env: "JWT_ISSUER", usage: "expected issuer of the tokens", set: setString(func(c *Config) *string { return &c.Auth.JWT.Issuer })},
+	{flag: "jwt-audience", env: "JWT_AUDIENCE", usage: "expected audience of the tokens", set: setString(func(c *Config) *string { return &c.Auth.JWT.Audience })},
+	{flag: "jwt-leeway", env: "JWT_LEEWAY", usage: "clock skew tolerated validating the tokens", set: setDuration(func(c *Config) *time.Duration { return &c.Auth.JWT.Leeway })},
+	{flag: "hmac-keys", env: "HMAC_KEYS_FILE", usage: "path of the json file with the keys of the signed requests", set: setString(func(c *Config) *string { return &c.Auth.HMAC.KeysFile })},
+	{flag: "hmac-window", env: "HMAC_WINDOW", usage: "maximum clock difference of a signed request", set: setDuration(func(c *Config) *time.Duration { return &c.Auth.HMAC.Window })},
+	{flag: "hmac-groups", env: "HMAC_GROUPS", usage: "comma separated route groups accepting signed requests: write, delete", set: setList(func(c *Config) *[]string { return &c.Auth.HMAC.Groups })},
+	{flag: "log-level", env: "PRODUCTS_LOG_LEVEL", usage: "minimum level of the logs: debug, info, warn or error", set: setString(func(c *Config) *string { return &c.Log.Level })},
+	{flag: "log-format", env: "PRODUCTS_LOG_FORMAT", usage: "format of the logs: json or text", set: setString(func(c *Config) *string { return &c.Log.Format })},
+	{flag: "log-output", env: "PRODUCTS_LOG_OUTPUT", usage: "sink of the logs: stdout, stderr or the path of a file", set: setString(func(c *Config) *string { return &c.Log.Output })},
+	{flag: "log-skip-paths", env: "PRODUCTS_LOG_SKIP_PATHS", usage: "comma separated paths left out of the access log", set: setList(func(c *Config) *[]string { return &c.Log.SkipPaths })},
+	{flag: "cors-origins", env: "PRODUCTS_CORS_ORIGINS", usage: "comma separated origins allowed to call the api (* for any)", set: setList(func(c *Config) *[]string { return &c.CORS.AllowedOrigins })},
+	{flag: "cors-credentials", env: "PRODUCTS_CORS_CREDENTIALS", usage: "allow the cookies and the authorization header in the cross-origin requests", isBool: true, set: setBool(func(c *Config) *bool { return &c.CORS.AllowCredentials })},
+	{flag: "rate-limits", env: "PRODUCTS_RATE_LIMITS", usage: "comma separated limits by route group or route, e.g. read=600/1m,GET /products=100/1m:20", set: setLimits},
+	{flag: "rate-limit-trust-proxy", env: "PRODUCTS_RATE_LIMIT_TRUST_PROXY", usage: "identify the clients without authentication by X-Forwarded-For (only behind a trusted proxy)", isBool: true, set: setBool(func(c *Config) *bool { return &c.RateLimit.TrustProxy })},
+}
+
+// Load merges the configuration layers and validates the result
+// - args are the command line arguments (without the program name) and getenv reads the environment
+// - the config file is set by the flag -config or the environment variable PRODUCTS_CONFIG
+func Load(args []string, getenv func(string) string) (cfg Config, err error) {
+	// flags: they are parsed first (to find the config file) but applied last
+	type flagValue struct {
+		s     setting
+		value string
+	}
+	var flagValues []flagValue
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", getenv(EnvConfigFile), "path of the yaml / json config file (env "+EnvConfigFile+")")
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		s := s
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		record := func(value string) error {
+			flagValues = append(flagValues, flagValue{s: s, value: value})
+			return nil
+		}
+		if s.isBool {
+			fs.BoolFunc(s.flag, usage, record)
+		} else {
+			fs.Func(s.flag, usage, record)
+		}
+	}
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	// defaults
+	cfg = Default()
+
+	// config file
+	if *configFile != "" {
+		if err = loadFile(*configFile, &cfg); err != nil {
+			return
+		}
+	}
+
+	// environment
+	for _, s := range settings {
+		value := getenv(s.env)
+		if value == "" {
+			continue
+		}
+		if err = s.set(&cfg, value); err != nil {
+			return cfg, fmt.Errorf("%w: env %s: %v", ErrConfigInvalid, s.env, err)
+		}
+	}
+
+	// flags
+	for _, f := range flagValues {
+		if err = f.s.set(&cfg, f.value); err != nil {
+			return cfg, fmt.Errorf("%w: flag -%s: %v", ErrConfigInvalid, f.s.flag, err)
+		}
+	}
+
+	err = cfg.Validate()
+	return
+}
+
+// loadFile reads a yaml config file into cfg, overriding only the keys present in the file
+// - json is valid yaml, so a json file works too
+func loadFile(path string, cfg *Config) (err error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConfigFile, err)
+	}
+
+	// the limits of the file replace the default ones as a whole (yaml would merge them)
+	limits := cfg.RateLimit.Limits
+	cfg.RateLimit.Limits = nil
+	if err = yaml.Unmarshal(bytes, cfg); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrConfigFile, path, err)
+	}
+	if cfg.RateLimit.Limits == nil {
+		cfg.RateLimit.Limits = limits
+	}
+	return
+}
+
+// Validate returns all the invalid values of the configuration
+func (c Config) Validate() (err error) {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// server
+	if c.Server.Addr == "" {
+		invalid("server.addr is required")
+	}
+	for name, timeout := range map[string]time.Duration{
+		"server.read_timeout":        c.Server.ReadTimeout,
+		"server.read_header_timeout": c.Server.ReadHeaderTimeout,
+		"server.write_timeout":       c.Server.WriteTimeout,
+		"server.idle_timeout":        c.Server.IdleTimeout,
+		"server.shutdown_timeout":    c.Server.ShutdownTimeout,
+	} {
+		if timeout <= 0 {
+			invalid("%s must be positive", name)
+		}
+	}
+
+	// storage
+	if c.Storage.Path == "" {
+		invalid("storage.path is required")
+	}
+	if c.Storage.Backend != "json" && c.Storage.Backend != "journal" {
+		invalid("storage.backend must be json or journal, not %q", c.Storage.Backend)
+	}
+	if c.Storage.CompactEvery < 0 {
+		invalid("storage.compact_every must not be negative")
+	}
+
+	// auth
+	if c.Auth.Token == "" && c.Auth.KeysFile == "" && !c.Auth.AllowEmptyToken {
+		invalid("auth.token (env API_TOKEN) or auth.keys_file is required, unless auth.allow_empty_token is set")
+	}
+	if c.Auth.JWT.Leeway < 0 {
+		invalid("auth.jwt.leeway must not be negative")
+	}
+	for _, group := range c.Auth.HMAC.Groups {
+		if group != application.GroupWrite && group != application.GroupDelete {
+			invalid("auth.hmac.groups must be %s or %s, not %q", application.GroupWrite, application.GroupDelete, group)
+		}
+	}
+
+	// log
+	var level slog.Level
+	if errLevel := level.UnmarshalText([]byte(c.Log.Level)); errLevel != nil {
+		invalid("log.level must be debug, info, warn or error, not %q", c.Log.Level)
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		invalid("log.format must be json or text, not %q", c.Log.Format)
+	}
+	if c.Log.Output == "" {
+		invalid("log.output is required (stdout, stderr or the path of a file)")
+	}
+
+	// cors
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		invalid("cors.allow_credentials is not allowed with the origin *")
+	}
+
+	// rate limit
+	for route, limit := range c.RateLimit.Limits {
+		if _, errLimit := middleware.ParseRateLimit(limit); errLimit != nil {
+			invalid("rate_limit.limits[%s]: %v", route, errLimit)
+		}
+	}
+
+	if len(errs) > 0 {
+		err = fmt.Errorf("%w: %w", ErrConfigInvalid, errors.Join(errs...))
+	}
+	return
+}
+
+// Application returns the configuration of the http server
+// - the configuration must be valid
+func (c Config) Application() (cfg application.ConfigDefaultHTTP) {
+	limits := make(map[string]middleware.RateLimit, len(c.RateLimit.Limits))
+	for route, limit := range c.RateLimit.Limits {
+		limits[route], _ = middleware.ParseRateLimit(limit)
+	}
+
+	return application.ConfigDefaultHTTP{
+		Addr:              c.Server.Addr,
+		ReadTimeout:       c.Server.ReadTimeout,
+		ReadHeaderTimeout: c.Server.ReadHeaderTimeout,
+		WriteTimeout:      c.Server.WriteTimeout,
+		IdleTimeout:       c.Server.IdleTimeout,
+		ShutdownTimeout:   c.Server.ShutdownTimeout,
+		Token:             c.Auth.Token,
+		AllowEmptyToken:   c.Auth.AllowEmptyToken,
+		KeysFile:          c.Auth.KeysFile,
+		JWT: middleware.ConfigJWT{
+			Secret:   c.Auth.JWT.Secret,
+			JWKSFile: c.Auth.JWT.JWKSFile,
+			Issuer:   c.Auth.JWT.Issuer,
+			Audience: c.Auth.JWT.Audience,
+			Leeway:   c.Auth.JWT.Leeway,
+		},
+		HMACKeysFile: c.Auth.HMAC.KeysFile,
+		HMACWindow:   c.Auth.HMAC.Window,
+		HMACGroups:   c.Auth.HMAC.Groups,
+		CORS: middleware.ConfigCORS{
+			AllowedOrigins:   c.CORS.AllowedOrigins,
+			AllowedMethods:   c.CORS.AllowedMethods,
+			AllowedHeaders:   c.CORS.AllowedHeaders,
+			ExposedHeaders:   c.CORS.ExposedHeaders,
+			AllowCredentials: c.CORS.AllowCredentials,
+			MaxAge:           c.CORS.MaxAge,
+		},
+		RateLimits:          limits,
+		RateLimitTrustProxy: c.RateLimit.TrustProxy,
+		FilePath:            c.Storage.Path,
+		StorageBackend:      c.Storage.Backend,
+		CompactEvery:        c.Storage.CompactEvery,
+		LogLevel:            c.Log.Level,
+		LogFormat:           c.Log.Format,
+		LogOutput:           c.Log.Output,
+		LogSkipPaths:        c.Log.SkipPaths,
+	}
+}
+
+// setString returns a setter of a string value
+func setString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+// setInt returns a setter of an int value
+func setInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) (err error) {
+		*field(c), err = strconv.Atoi(value)
+		return
+	}
+}
+
+// setBool returns a setter of a bool value
+func setBool(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) (err error) {
+		*field(c), err = strconv.ParseBool(value)
+		return
+	}
+}
+
+// setDuration returns a setter of a duration value (e.g. "30s")
+func setDuration(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) (err error) {
+		*field(c), err = time.ParseDuration(value)
+		return
+	}
+}
+
+// setList returns a setter of a comma separated list (an empty value empties the list)
+func setList(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+// setLimits sets the rate limits of a comma separated list of "route=limit", replacing the previous ones
+func setLimits(c *Config, value string) (err error) {
+	limits := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		route, limit, found := strings.Cut(entry, "=")
+		if !found {
+			return fmt.Errorf("%q is not route=limit", entry)
+		}
+		limits[strings.TrimSpace(route)] = strings.TrimSpace(limit)
+	}
+	c.RateLimit.Limits = limits
+	return
+}
@@ -0,0 +1,146 @@
+package config_test
+
+import (
+	"app/internal/config"
+	"app/internal/middleware"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// env returns a getenv of a map
+func env(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("success 01 - defaults", func(t *testing.T) {
+		// act
+		cfg, err := config.Load(nil, env(map[string]string{"API_TOKEN": "123456"}))
+
+		// assert
+		require.NoError(t, err)
+		expected := config.Default()
+		expected.Auth.Token = "123456"
+		require.Equal(t, expected, cfg)
+	})
+
+	t.Run("success 02 - the file overrides the defaults, the environment the file and the flags the environment", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(path, []byte("server:\n  addr: \":9000\"\n  write_timeout: 1m\nstorage:\n  backend: journal\n  path: file.json\nrate_limit:\n  limits:\n    read: 10/1s\n"), 0644)
+		require.NoError(t, err)
+
+		// act
+		cfg, err := config.Load(
+			[]string{"-config", path, "-addr", ":9002", "-cors-origins", "https://a.example.com, https://b.example.com"},
+			env(map[string]string{"API_TOKEN": "123456", "PRODUCTS_ADDR": ":9001", "PRODUCTS_FILE": "env.json"}),
+		)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, ":9002", cfg.Server.Addr)
+		require.Equal(t, time.Minute, cfg.Server.WriteTimeout)
+		require.Equal(t, 5*time.Second, cfg.Server.ReadHeaderTimeout)
+		require.Equal(t, "journal", cfg.Storage.Backend)
+		require.Equal(t, "env.json", cfg.Storage.Path)
+		require.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.CORS.AllowedOrigins)
+		require.Equal(t, map[string]string{"read": "10/1s"}, cfg.RateLimit.Limits)
+	})
+
+	t.Run("success 03 - json config file, converted to the configuration of the server", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "config.json")
+		err := os.WriteFile(path, []byte(`{"auth":{"keys_file":"keys.json"},"rate_limit":{"limits":{"write":"5/1s:10"}}}`), 0644)
+		require.NoError(t, err)
+
+		// act
+		cfg, err := config.Load(nil, env(map[string]string{"PRODUCTS_CONFIG": path}))
+
+		// assert
+		require.NoError(t, err)
+		app := cfg.Application()
+		require.Equal(t, "keys.json", app.KeysFile)
+		require.Equal(t, map[string]middleware.RateLimit{"write": {Requests: 5, Per: time.Second, Burst: 10}}, app.RateLimits)
+	})
+
+	t.Run("success 04 - compaction disabled by the environment", func(t *testing.T) {
+		// act
+		cfg, err := config.Load(nil, env(map[string]string{"API_TOKEN": "123456", "PRODUCTS_COMPACT_EVERY": "0"}))
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 0, cfg.Application().CompactEvery)
+	})
+
+	t.Run("success 05 - limits of the flags replace the default ones as a whole", func(t *testing.T) {
+		// act
+		cfgDefault, errDefault := config.Load(nil, env(map[string]string{"API_TOKEN": "123456"}))
+		cfg, err := config.Load([]string{"-rate-limits", "read=600/1m, GET /products=10/1s:20"}, env(map[string]string{"API_TOKEN": "123456"}))
+
+		// assert
+		require.NoError(t, errDefault)
+		require.Equal(t, map[string]middleware.RateLimit{
+			"read":   {Requests: 600, Per: time.Minute},
+			"write":  {Requests: 60, Per: time.Minute},
+			"delete": {Requests: 60, Per: time.Minute},
+			"auth":   {Requests: 120, Per: time.Minute},
+		}, cfgDefault.Application().RateLimits)
+		require.NoError(t, err)
+		require.Equal(t, map[string]middleware.RateLimit{
+			"read":          {Requests: 600, Per: time.Minute},
+			"GET /products": {Requests: 10, Per: time.Second, Burst: 20},
+		}, cfg.Application().RateLimits)
+	})
+
+	t.Run("failure 01 - invalid values are reported together", func(t *testing.T) {
+		// act
+		_, err := config.Load(
+			[]string{"-storage", "sql", "-log-level", "loud", "-rate-limits", "read=fast"},
+			env(map[string]string{}),
+		)
+
+		// assert
+		require.ErrorIs(t, err, config.ErrConfigInvalid)
+		require.ErrorContains(t, err, "storage.backend")
+		require.ErrorContains(t, err, "log.level")
+		require.ErrorContains(t, err, "rate_limit.limits[read]")
+		require.ErrorContains(t, err, "auth.token")
+	})
+
+	t.Run("failure 02 - timeouts that are not positive are rejected", func(t *testing.T) {
+		// act
+		_, err := config.Load([]string{"-shutdown-timeout", "0s"}, env(map[string]string{"API_TOKEN": "123456"}))
+
+		// assert
+		require.ErrorIs(t, err, config.ErrConfigInvalid)
+		require.ErrorContains(t, err, "server.shutdown_timeout")
+	})
+
+	t.Run("failure 03 - malformed value of the environment", func(t *testing.T) {
+		// act
+		_, err := config.Load(nil, env(map[string]string{"API_TOKEN": "123456", "PRODUCTS_READ_TIMEOUT": "soon"}))
+
+		// assert
+		require.ErrorIs(t, err, config.ErrConfigInvalid)
+		require.ErrorContains(t, err, "PRODUCTS_READ_TIMEOUT")
+	})
+
+	t.Run("failure 04 - malformed config file", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		err := os.WriteFile(path, []byte("server: [\n"), 0644)
+		require.NoError(t, err)
+
+		// act
+		_, err = config.Load([]string{"-config", path}, env(map[string]string{}))
+
+		// assert
+		require.ErrorIs(t, err, config.ErrConfigFile)
+	})
+}
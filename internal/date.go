@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// layouts of a date
+const (
+	// DateLayout is the layout used to write a date (dd/mm/yyyy)
+	DateLayout = "02/01/2006"
+	// DateLayoutISO is the ISO-8601 layout also accepted when reading a date
+	DateLayoutISO = "2006-01-02"
+)
+
+// Date is a calendar date (without time of day) in UTC
+// - it is written as dd/mm/yyyy and read as dd/mm/yyyy or ISO-8601 (yyyy-mm-dd or a full timestamp)
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the date of a year, month and day
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// ParseDate parses a date written as dd/mm/yyyy or ISO-8601
+func ParseDate(s string) (date Date, err error) {
+	for _, layout := range []string{DateLayout, DateLayoutISO, time.RFC3339} {
+		t, errParse := time.Parse(layout, s)
+		if errParse == nil {
+			return NewDate(t.Year(), t.Month(), t.Day()), nil
+		}
+	}
+	err = fmt.Errorf("%w: %q", ErrInvalidExpiration, s)
+	return
+}
+
+// String returns the date as dd/mm/yyyy (empty if it is zero)
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateLayout)
+}
+
+// MarshalJSON writes the date as dd/mm/yyyy
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON reads the date as dd/mm/yyyy or ISO-8601 (an empty string or null is the zero date)
+func (d *Date) UnmarshalJSON(b []byte) (err error) {
+	var s *string
+	if err = json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidExpiration, b)
+	}
+	if s == nil || *s == "" {
+		*d = Date{}
+		return
+	}
+
+	*d, err = ParseDate(*s)
+	return
+}
@@ -0,0 +1,44 @@
+package internal_test
+
+import (
+	"app/internal"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDate_UnmarshalJSON(t *testing.T) {
+	t.Run("success 01 - should read dd/mm/yyyy and ISO-8601", func(t *testing.T) {
+		// arrange
+		expectedDate := internal.NewDate(2024, 5, 14)
+
+		for _, input := range []string{`"14/05/2024"`, `"2024-05-14"`, `"2024-05-14T10:30:00Z"`} {
+			// act
+			var date internal.Date
+			err := json.Unmarshal([]byte(input), &date)
+
+			// assert
+			require.NoError(t, err)
+			require.Equal(t, expectedDate, date)
+		}
+	})
+
+	t.Run("failure 01 - invalid date", func(t *testing.T) {
+		// act
+		var date internal.Date
+		err := json.Unmarshal([]byte(`"31/02/2024"`), &date)
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrInvalidExpiration)
+	})
+}
+
+func TestDate_MarshalJSON(t *testing.T) {
+	// act
+	b, err := json.Marshal(internal.NewDate(2024, 5, 14))
+
+	// assert
+	require.NoError(t, err)
+	require.Equal(t, `"14/05/2024"`, string(b))
+}
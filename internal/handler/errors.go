@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"app/internal"
+	"app/platform/web/request"
+	"app/platform/web/response"
+	"errors"
+	"net/http"
+)
+
+// in this file i handle how the errors are written as problem details (RFC 7807)
+
+// errors
+var (
+	ErrInvalidId = errors.New("id must be an integer")
+	// ErrPreconditionFailed is returned when the If-Match of a request does not match the current product
+	ErrPreconditionFailed = errors.New("the product does not match If-Match")
+)
+
+// ProblemTypePrefix is the prefix of the URI of every problem type
+const ProblemTypePrefix = "urn:go-web:problem:"
+
+// problemType is the http representation of a sentinel error
+type problemType struct {
+	// err is the sentinel error
+	err error
+	// status is the http status code
+	status int
+	// name is appended to ProblemTypePrefix to build the type URI
+	name string
+	// title is a short summary of the problem type
+	title string
+}
+
+// problemTypes maps the sentinel errors to problem types (the first match wins)
+// - a repeated code value is reported by the service as a violation of the validation (422)
+var problemTypes = []problemType{
+	{internal.ErrProductInvalid, http.StatusUnprocessableEntity, "validation", "Product is not valid"},
+	{internal.ErrProductNotFound, http.StatusNotFound, "product-not-found", "Product not found"},
+	{internal.ErrVersionConflict, http.StatusConflict, "version-conflict", "Product was modified by another request"},
+	{internal.ErrInvalidQuery, http.StatusBadRequest, "invalid-query", "Invalid query"},
+	{ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition-failed", "Precondition failed"},
+	{ErrInvalidId, http.StatusBadRequest, "invalid-id", "Invalid id"},
+	{request.ErrRequestContentTypeNotJSON, http.StatusUnsupportedMediaType, "unsupported-media-type", "Unsupported media type"},
+	{request.ErrRequestJSONInvalid, http.StatusBadRequest, "invalid-body", "Invalid body"},
+	{internal.ErrProductStorage, http.StatusInternalServerError, "storage-failure", "Cannot persist the product"},
+}
+
+// NewProblem returns the problem details of an error
+// - the detail of internal server errors is not exposed
+func NewProblem(r *http.Request, err error) (problem response.ProblemDetails) {
+	problem = response.ProblemDetails{
+		Type:     ProblemTypePrefix + "internal",
+		Title:    "Internal server error",
+		Status:   http.StatusInternalServerError,
+		Instance: r.URL.Path,
+	}
+
+	for _, pt := range problemTypes {
+		if !errors.Is(err, pt.err) {
+			continue
+		}
+		problem.Type = ProblemTypePrefix + pt.name
+		problem.Title = pt.title
+		problem.Status = pt.status
+		if pt.status < http.StatusInternalServerError {
+			problem.Detail = err.Error()
+		}
+		break
+	}
+
+	// violations of a validation
+	var verr *internal.ValidationError
+	if errors.As(err, &verr) {
+		problem.Detail = ""
+		problem.Extensions = map[string]any{"errors": verr.Violations}
+	}
+
+	return
+}
+
+// ResponseError writes an error as problem details
+func ResponseError(w http.ResponseWriter, r *http.Request, err error) {
+	response.Problem(w, NewProblem(r, err))
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"app/internal"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// in this file i handle the ETags and the conditional requests (RFC 9110)
+// - a product is tagged by its version, maintained by the repository, and a hash of its content
+//   (the ids are not persisted, so after a restart an id can be reused by another product at the same version)
+// - a list is tagged by a hash of its body, as it depends on the query too
+
+// ProductETag returns the strong ETag of a product
+func ProductETag(product internal.Product) string {
+	bytes, _ := json.Marshal(product)
+	sum := sha256.Sum256(bytes)
+	return fmt.Sprintf(`"%d-%d-%s"`, product.Id, product.Version, hex.EncodeToString(sum[:8]))
+}
+
+// bodyETag returns the weak ETag of a body
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matchETag returns if a If-Match / If-None-Match header matches an ETag
+// - If-None-Match compares weakly (ignoring W/), If-Match strongly (a weak ETag never matches)
+func matchETag(header, etag string, strong bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if strong && strings.HasPrefix(candidate, "W/") {
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified writes a 304 if the If-None-Match of the request matches the ETag
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !matchETag(header, etag, false) {
+		return false
+	}
+
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// responseJSONTagged writes a json body tagged by its hash, or a 304 if the client already has it
+func responseJSONTagged(w http.ResponseWriter, r *http.Request, code int, body any) {
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	etag := bodyETag(bytes)
+	if notModified(w, r, etag) {
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(bytes)
+}
+
+// checkIfMatch returns ErrPreconditionFailed if the request has an If-Match that does not match the current product
+// - expectedVersion is the version matched, so the write fails if the product changes before it (AnyVersion without If-Match)
+func (d *DefaultProducts) checkIfMatch(r *http.Request, id int) (expectedVersion int, err error) {
+	if r.Header.Get("If-Match") == "" {
+		return internal.AnyVersion, nil
+	}
+
+	product, err := d.sv.GetById(r.Context(), id)
+	if err = ifMatch(r, product, err); err != nil {
+		return
+	}
+	return product.Version, nil
+}
+
+// ifMatch checks the If-Match of the request against a product read from the service (or the error reading it)
+// - a missing product never matches, as there is no current representation
+func ifMatch(r *http.Request, product internal.Product, errGet error) (err error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return errGet
+	}
+
+	if errors.Is(errGet, internal.ErrProductNotFound) {
+		return fmt.Errorf("%w: %v", ErrPreconditionFailed, errGet)
+	}
+	if errGet != nil {
+		return errGet
+	}
+
+	if !matchETag(header, ProductETag(product), true) {
+		return fmt.Errorf("%w: current ETag is %s", ErrPreconditionFailed, ProductETag(product))
+	}
+	return
+}
@@ -0,0 +1,160 @@
+package handler_test
+
+import (
+	"app/internal"
+	"app/internal/handler"
+	"app/internal/repository"
+	"app/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductsDefault_ConditionalRequests(t *testing.T) {
+	// arrange
+	current := internal.Product{Id: 1, Name: "product 1", Quantity: 10, Code_value: "123", Is_published: true, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(100, 0), Version: 3}
+	newHandler := func() *handler.DefaultProducts {
+		db := []internal.Product{current}
+		return handler.NewDefaultProducts(service.NewProductDefault(repository.NewProductsMap(db)))
+	}
+	// stale is the ETag of the previous version of the product
+	stale := current
+	stale.Version = 2
+	stale.Quantity = 8
+	body := `{"name": "product 1", "quantity": 5, "code_value": "123", "is_published": true, "expiration": "14/05/2024", "price": 100}`
+
+	t.Run("success 01 - should respond 304 to a client with the current product", func(t *testing.T) {
+		// act
+		req := NewRequest("GET", "/products/1", nil, map[string]string{"id": "1"}, nil)
+		req.Header.Set("If-None-Match", handler.ProductETag(stale)+", "+handler.ProductETag(current))
+		res := httptest.NewRecorder()
+		newHandler().GetById()(res, req)
+
+		// assert
+		require.Equal(t, http.StatusNotModified, res.Code)
+		require.Equal(t, handler.ProductETag(current), res.Header().Get("ETag"))
+		require.Empty(t, res.Body.String())
+	})
+
+	t.Run("success 02 - should respond 304 to a client with the current list", func(t *testing.T) {
+		// arrange
+		hd := newHandler()
+		res01 := httptest.NewRecorder()
+		hd.GetAll()(res01, NewRequest("GET", "/products", nil, nil, nil))
+
+		// act
+		req := NewRequest("GET", "/products", nil, nil, nil)
+		req.Header.Set("If-None-Match", res01.Header().Get("ETag"))
+		res02 := httptest.NewRecorder()
+		hd.GetAll()(res02, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, res01.Code)
+		require.Equal(t, http.StatusNotModified, res02.Code)
+		require.Empty(t, res02.Body.String())
+	})
+
+	t.Run("success 03 - should update the product matching If-Match, with a new ETag", func(t *testing.T) {
+		// act
+		req := NewRequest("PUT", "/products/1", strings.NewReader(body), map[string]string{"id": "1"}, nil)
+		req.Header.Set("If-Match", handler.ProductETag(current))
+		res := httptest.NewRecorder()
+		newHandler().Update()(res, req)
+
+		// assert
+		updated := current
+		updated.Quantity = 5
+		updated.Version = 4
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Equal(t, handler.ProductETag(updated), res.Header().Get("ETag"))
+	})
+
+	t.Run("success 04 - should update the product read when the version of the body is null or omitted", func(t *testing.T) {
+		for _, body := range []string{`{"quantity": 5, "version": null}`, `{"quantity": 5}`} {
+			// act
+			req := NewRequest("PATCH", "/products/1", strings.NewReader(body), map[string]string{"id": "1"}, nil)
+			res := httptest.NewRecorder()
+			newHandler().UpdatePartial()(res, req)
+
+			// assert
+			require.Equal(t, http.StatusOK, res.Code, body)
+			require.Contains(t, res.Body.String(), `"Version":4`)
+		}
+	})
+
+	t.Run("failure 01 - should not update a product modified since it was read", func(t *testing.T) {
+		// act
+		req := NewRequest("PATCH", "/products/1", strings.NewReader(`{"quantity": 5}`), map[string]string{"id": "1"}, nil)
+		req.Header.Set("If-Match", handler.ProductETag(stale))
+		res := httptest.NewRecorder()
+		newHandler().UpdatePartial()(res, req)
+
+		// assert
+		require.Equal(t, http.StatusPreconditionFailed, res.Code)
+		require.Contains(t, res.Body.String(), "urn:go-web:problem:precondition-failed")
+	})
+
+	t.Run("failure 02 - should not delete a product modified since it was read", func(t *testing.T) {
+		// arrange
+		hd := newHandler()
+
+		// act
+		req := NewRequest("DELETE", "/products/1", nil, map[string]string{"id": "1"}, nil)
+		req.Header.Set("If-Match", "W/"+handler.ProductETag(current))
+		res := httptest.NewRecorder()
+		hd.Delete()(res, req)
+
+		// assert
+		require.Equal(t, http.StatusPreconditionFailed, res.Code)
+		resGet := httptest.NewRecorder()
+		hd.GetById()(resGet, NewRequest("GET", "/products/1", nil, map[string]string{"id": "1"}, nil))
+		require.Equal(t, http.StatusOK, resGet.Code)
+	})
+
+	t.Run("failure 03 - should check If-Match before the body, a stale client with an invalid body gets a 412", func(t *testing.T) {
+		// act
+		req := NewRequest("PUT", "/products/1", strings.NewReader(`{"name": ""}`), map[string]string{"id": "1"}, nil)
+		req.Header.Set("If-Match", handler.ProductETag(stale))
+		res := httptest.NewRecorder()
+		newHandler().Update()(res, req)
+
+		// assert
+		require.Equal(t, http.StatusPreconditionFailed, res.Code)
+	})
+
+	t.Run("failure 04 - should not match another product with the same id and version (e.g. an id reused after a restart)", func(t *testing.T) {
+		// arrange
+		other := current
+		other.Name = "product 2"
+		other.Code_value = "456"
+
+		// act
+		req := NewRequest("DELETE", "/products/1", nil, map[string]string{"id": "1"}, nil)
+		req.Header.Set("If-Match", handler.ProductETag(other))
+		res := httptest.NewRecorder()
+		newHandler().Delete()(res, req)
+
+		// assert
+		require.Equal(t, http.StatusPreconditionFailed, res.Code)
+	})
+
+	t.Run("failure 05 - should respond 409 to an update of a version that is not the current", func(t *testing.T) {
+		// arrange
+		hd := newHandler()
+
+		// act
+		req := NewRequest("PATCH", "/products/1", strings.NewReader(`{"quantity": 5, "version": 2}`), map[string]string{"id": "1"}, nil)
+		res := httptest.NewRecorder()
+		hd.UpdatePartial()(res, req)
+
+		// assert
+		require.Equal(t, http.StatusConflict, res.Code)
+		require.Contains(t, res.Body.String(), "urn:go-web:problem:version-conflict")
+		resGet := httptest.NewRecorder()
+		hd.GetById()(resGet, NewRequest("GET", "/products/1", nil, map[string]string{"id": "1"}, nil))
+		require.Contains(t, resGet.Body.String(), `"Quantity":10`)
+	})
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"app/platform/web/response"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// in this file i handle the endpoints probed by the orchestrator, which do not depend on the products service
+
+// HealthRepository is the part of the repository checked by the health endpoints
+type HealthRepository interface {
+	// Check returns an error if the storage cannot persist the changes
+	Check() (err error)
+	// Count returns the number of products
+	Count() int
+}
+
+// NewDefaultHealth returns a new DefaultHealth instance
+func NewDefaultHealth(rp HealthRepository, start time.Time) *DefaultHealth {
+	return &DefaultHealth{
+		rp:    rp,
+		start: start,
+	}
+}
+
+// DefaultHealth is an implementation with handlers for the health of the server
+type DefaultHealth struct {
+	// rp is the repository of the products
+	rp HealthRepository
+	// start is the moment the server started
+	start time.Time
+}
+
+// Healthz responds while the process is alive
+func (d *DefaultHealth) Healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, http.StatusOK, map[string]any{"status": "ok"})
+	}
+}
+
+// Readyz responds 200 when the products are loaded and the storage is writable, 503 otherwise
+// - the server only listens once the products are loaded, so data is ok while it responds
+func (d *DefaultHealth) Readyz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		checks := map[string]string{"data": "ok", "storage": "ok"}
+		status, code := "ready", http.StatusOK
+
+		if err := d.rp.Check(); err != nil {
+			checks["storage"] = err.Error()
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+
+		response.JSON(w, code, map[string]any{"status": status, "checks": checks})
+	}
+}
+
+// Version responds the build info of the binary, the start time and the number of products
+func (d *DefaultHealth) Version() http.HandlerFunc {
+	// the build info does not change while running
+	build := map[string]any{}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		build["path"] = info.Main.Path
+		build["version"] = info.Main.Version
+		build["go_version"] = info.GoVersion
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision", "vcs.time", "vcs.modified":
+				build[setting.Key] = setting.Value
+			}
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, http.StatusOK, map[string]any{
+			"build":      build,
+			"start_time": d.start.UTC().Format(time.RFC3339),
+			"uptime":     time.Since(d.start).Round(time.Second).String(),
+			"products":   d.rp.Count(),
+		})
+	}
+}
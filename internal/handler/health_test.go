@@ -0,0 +1,74 @@
+package handler_test
+
+import (
+	"app/internal/handler"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// HealthRepositoryStub is a stub of handler.HealthRepository
+type HealthRepositoryStub struct {
+	errCheck error
+	count    int
+}
+
+func (s *HealthRepositoryStub) Check() (err error) {
+	return s.errCheck
+}
+
+func (s *HealthRepositoryStub) Count() int {
+	return s.count
+}
+
+func TestDefaultHealth_Readyz(t *testing.T) {
+	t.Run("success 01 - should be ready with the storage writable", func(t *testing.T) {
+		// arrange
+		hd := handler.NewDefaultHealth(&HealthRepositoryStub{count: 2}, time.Now())
+
+		// act
+		res := httptest.NewRecorder()
+		hd.Readyz()(res, httptest.NewRequest("GET", "/readyz", nil))
+
+		// assert
+		expectedBody := `{"status":"ready","checks":{"data":"ok","storage":"ok"}}`
+		require.Equal(t, http.StatusOK, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+
+	t.Run("failure 01 - storage not writable", func(t *testing.T) {
+		// arrange
+		hd := handler.NewDefaultHealth(&HealthRepositoryStub{errCheck: errors.New("read-only file system")}, time.Now())
+
+		// act
+		res := httptest.NewRecorder()
+		hd.Readyz()(res, httptest.NewRequest("GET", "/readyz", nil))
+
+		// assert
+		expectedBody := `{"status":"unavailable","checks":{"data":"ok","storage":"read-only file system"}}`
+		require.Equal(t, http.StatusServiceUnavailable, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+}
+
+func TestDefaultHealth_Version(t *testing.T) {
+	t.Run("success 01 - should respond the start time and the number of products", func(t *testing.T) {
+		// arrange
+		start := time.Date(2024, 5, 14, 10, 0, 0, 0, time.UTC)
+		hd := handler.NewDefaultHealth(&HealthRepositoryStub{count: 2}, start)
+
+		// act
+		res := httptest.NewRecorder()
+		hd.Version()(res, httptest.NewRequest("GET", "/version", nil))
+
+		// assert
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Contains(t, res.Body.String(), `"start_time":"2024-05-14T10:00:00Z"`)
+		require.Contains(t, res.Body.String(), `"products":2`)
+		require.Contains(t, res.Body.String(), `"build":`)
+	})
+}
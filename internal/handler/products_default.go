@@ -6,9 +6,9 @@ import (
 	"app/platform/web/response"
 	"encoding/json"
 	"errors"
-	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -29,13 +29,119 @@ type DefaultProducts struct {
 }
 
 // this is an struct to represent the body of the request
+// - expiration and price are kept raw, so an invalid format is reported as a violation of the field
+// - version is optional: the version of the product the update expects (it is not written, the repository maintains it)
 type BodyRequestProductJSON struct {
-	Name         string  `json:"name"`
-	Quantity     int     `json:"quantity"`
-	Code_value   string  `json:"code_value"`
-	Is_published bool    `json:"is_published"`
-	Expiration   string  `json:"expiration"`
-	Price        float64 `json:"price"`
+	Name         string          `json:"name"`
+	Quantity     int             `json:"quantity"`
+	Code_value   string          `json:"code_value"`
+	Is_published bool            `json:"is_published"`
+	Expiration   string          `json:"expiration"`
+	Price        json.RawMessage `json:"price"`
+	Version      *int            `json:"version,omitempty"`
+}
+
+// NewBodyRequestProductJSON serializes a product to a body
+func NewBodyRequestProductJSON(product internal.Product) BodyRequestProductJSON {
+	return BodyRequestProductJSON{
+		Name:         product.Name,
+		Quantity:     product.Quantity,
+		Code_value:   product.Code_value,
+		Is_published: product.Is_published,
+		Expiration:   product.Expiration.String(),
+		Price:        json.RawMessage(product.Price.String()),
+		Version:      &product.Version,
+	}
+}
+
+// fields of the body of a product
+var (
+	// bodyFields are the fields written by a full representation of a product (a PUT must send all of them)
+	bodyFields = []string{"name", "quantity", "code_value", "is_published", "expiration", "price"}
+	// bodyFieldTypes are the json types of the fields, used in the message of a field of another type
+	bodyFieldTypes = map[string]string{
+		"name":         "a string",
+		"quantity":     "an integer",
+		"code_value":   "a string",
+		"is_published": "a boolean",
+		"expiration":   "a valid date (dd/mm/yyyy or yyyy-mm-dd)",
+		"version":      "an integer",
+	}
+)
+
+// decode decodes the body of the request field by field, on top of the current values of b
+// - a field of another json type is reported as a format violation of the field, instead of failing the whole body
+// - the required fields that are missing (or null) are reported as required violations
+func (b *BodyRequestProductJSON) decode(r *http.Request, required ...string) (verr *internal.ValidationError, err error) {
+	var fields map[string]json.RawMessage
+	if err = request.JSON(r, &fields); err != nil {
+		return
+	}
+
+	verr = &internal.ValidationError{}
+	for _, field := range required {
+		if raw, ok := fields[field]; !ok || string(raw) == "null" {
+			verr.Add(field, internal.RuleRequired, fieldLabel(field)+" is required")
+		}
+	}
+
+	targets := map[string]any{
+		"name":         &b.Name,
+		"quantity":     &b.Quantity,
+		"code_value":   &b.Code_value,
+		"is_published": &b.Is_published,
+		"expiration":   &b.Expiration,
+		"version":      &b.Version,
+	}
+	for _, field := range append(bodyFields, "version") {
+		raw, ok := fields[field]
+		if !ok || verr.Has(field) {
+			continue
+		}
+		// price is kept raw, its format is validated converting it
+		if field == "price" {
+			b.Price = raw
+			continue
+		}
+		if errField := json.Unmarshal(raw, targets[field]); errField != nil {
+			verr.Add(field, internal.RuleFormat, fieldLabel(field)+" must be "+bodyFieldTypes[field])
+		}
+	}
+	return
+}
+
+// fieldLabel returns the name of a field as written in the messages (e.g. "code value")
+func fieldLabel(field string) string {
+	return strings.ReplaceAll(field, "_", " ")
+}
+
+// Product deserializes the body to a product, returning the violations of the format of its fields
+// - the fields with an invalid format are left empty
+func (b BodyRequestProductJSON) Product(id int) (product internal.Product, verr *internal.ValidationError) {
+	verr = &internal.ValidationError{}
+	product = internal.Product{
+		Id:           id,
+		Name:         b.Name,
+		Quantity:     b.Quantity,
+		Code_value:   b.Code_value,
+		Is_published: b.Is_published,
+	}
+
+	if b.Expiration != "" {
+		expiration, err := internal.ParseDate(b.Expiration)
+		if err != nil {
+			verr.Add("expiration", internal.RuleFormat, "expiration must be a valid date (dd/mm/yyyy or yyyy-mm-dd)")
+		}
+		product.Expiration = expiration
+	}
+
+	if len(b.Price) > 0 {
+		if err := product.Price.UnmarshalJSON(b.Price); err != nil {
+			verr.Add("price", internal.RuleFormat, "price must be a valid amount")
+		}
+	}
+
+	return
 }
 
 // this is an struct to represent the response of the request
@@ -45,16 +151,54 @@ type ResponseProductJSON struct {
 	Quantity     int
 	Code_value   string
 	Is_published bool
-	Expiration   string
-	Price        float64
+	Expiration   internal.Date
+	Price        internal.Money
+	Version      int
 }
 
 func (d *DefaultProducts) GetAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		products := (*d).sv.GetAll()
+		// request
+		query, err := ParseProductQuery(r.URL.Query())
+		if err != nil {
+			ResponseError(w, r, err)
+			return
+		}
+
+		page, err := (*d).sv.Query(r.Context(), query)
+		if err != nil {
+			ResponseError(w, r, err)
+			return
+		}
 
 		// response
-		response.JSON(w, http.StatusOK, map[string]any{"message": "products found", "data": products})
+		responseJSONTagged(w, r, http.StatusOK, map[string]any{
+			"message": "products found",
+			"data":    page.Products,
+			"total":   page.Total,
+			"limit":   page.Limit,
+			"offset":  page.Offset,
+		})
+	}
+}
+
+func (d *DefaultProducts) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		search, err := ParseProductSearch(r.URL.Query())
+		if err != nil {
+			ResponseError(w, r, err)
+			return
+		}
+
+		products, err := (*d).sv.Search(r.Context(), search)
+		if err != nil {
+			ResponseError(w, r, err)
+			return
+		}
+
+		// response
+		responseJSONTagged(w, r, http.StatusOK, map[string]any{"message": "products found", "data": products})
 	}
 }
 
@@ -65,16 +209,43 @@ func (d *DefaultProducts) GetById() http.HandlerFunc {
 
 		id, err := strconv.Atoi(idString)
 		if err != nil {
-			response.Error(w, http.StatusInternalServerError, "error with the id")
+			ResponseError(w, r, ErrInvalidId)
 			return
 		}
 
-		product, err := (*d).sv.GetById(id)
+		product, err := (*d).sv.GetById(r.Context(), id)
+		if err != nil {
+			ResponseError(w, r, err)
+			return
+		}
+
+		// response (or 304 if the client has the current version)
+		etag := ProductETag(product)
+		if notModified(w, r, etag) {
+			return
+		}
+		w.Header().Set("ETag", etag)
+		response.JSON(w, http.StatusOK, map[string]any{"message": "product found", "data": product})
+	}
+}
+
+func (d *DefaultProducts) GetByCode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// request
+		code := chi.URLParam(r, "code")
+
+		product, err := (*d).sv.GetByCode(r.Context(), code)
 		if err != nil {
-			response.Error(w, http.StatusNotFound, "product not found")
+			ResponseError(w, r, err)
 			return
 		}
 
+		// response (or 304 if the client has the current version)
+		etag := ProductETag(product)
+		if notModified(w, r, etag) {
+			return
+		}
+		w.Header().Set("ETag", etag)
 		response.JSON(w, http.StatusOK, map[string]any{"message": "product found", "data": product})
 	}
 }
@@ -82,32 +253,22 @@ func (d *DefaultProducts) GetById() http.HandlerFunc {
 func (d *DefaultProducts) Create() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var body BodyRequestProductJSON
+		verr, err := body.decode(r)
+		if err != nil {
+			ResponseError(w, r, err)
+			return
+		}
 
-		if err := request.JSON(r, &body); err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid body")
+		// deserialize the product, validating the format of the fields
+		product, verrFormat := body.Product(0)
+		verr.Merge(verrFormat)
+		if verr.Err() != nil {
+			d.validationError(w, r, &product, verr)
 			return
 		}
 
-		product := internal.Product{
-			Name:         body.Name,
-			Quantity:     body.Quantity,
-			Code_value:   body.Code_value,
-			Is_published: body.Is_published,
-			Expiration:   body.Expiration,
-			Price:        body.Price,
-		}
-
-		if err := d.sv.Create(&product); err != nil {
-			switch {
-			case errors.Is(err, internal.ErrFieldsEmpty):
-				response.Error(w, http.StatusBadRequest, "fields cannot be empty")
-			case errors.Is(err, internal.ErrInvalidExpiration):
-				response.Error(w, http.StatusBadRequest, "expiration must be a valid date")
-			case errors.Is(err, internal.ErrRepeatedCode):
-				response.Error(w, http.StatusBadRequest, "code value must be unique")
-			default:
-				response.Error(w, http.StatusInternalServerError, "internal server error")
-			}
+		if err := d.sv.Create(r.Context(), &product); err != nil {
+			ResponseError(w, r, err)
 			return
 		}
 
@@ -120,8 +281,10 @@ func (d *DefaultProducts) Create() http.HandlerFunc {
 			Is_published: product.Is_published,
 			Expiration:   product.Expiration,
 			Price:        product.Price,
+			Version:      product.Version,
 		}
 
+		w.Header().Set("ETag", ProductETag(product))
 		response.JSON(w, http.StatusCreated, map[string]any{"message": "product created", "data": data})
 	}
 }
@@ -132,58 +295,40 @@ func (d *DefaultProducts) Update() http.HandlerFunc {
 		// get the id from path
 		id, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			response.Error(w, http.StatusBadRequest, "id is not an int")
+			ResponseError(w, r, ErrInvalidId)
 			return
 		}
 
-		// get the body from the request
-		bytes, err := io.ReadAll(r.Body)
+		// check the preconditions before the body, so a stale client gets a 412 whatever it sends
+		expectedVersion, err := d.checkIfMatch(r, id)
 		if err != nil {
-			response.Error(w, http.StatusBadRequest, "cannot read the body")
+			ResponseError(w, r, err)
 			return
 		}
 
-		// get body to map[string]any
-		var bodyMap map[string]any
-		if err := json.Unmarshal(bytes, &bodyMap); err != nil {
-			response.Error(w, http.StatusBadRequest, "cannot make a map from the body")
+		// get body
+		// - it is the full representation of the product, so every field is required
+		var body BodyRequestProductJSON
+		verr, err := body.decode(r, bodyFields...)
+		if err != nil {
+			ResponseError(w, r, err)
 			return
 		}
 
-		// validate body
-		if err := ValidateKeyExistante(bodyMap, "name", "quantity", "code_value", "is_published", "expiration", "price"); err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid key")
+		// deserialize the updated product, validating the format of the fields
+		product, verrFormat := body.Product(id)
+		verr.Merge(verrFormat)
+		if verr.Err() != nil {
+			d.validationError(w, r, &product, verr)
 			return
 		}
 
-		// get body
-		var body BodyRequestProductJSON
-		if err := json.Unmarshal(bytes, &body); err != nil {
-			response.Error(w, http.StatusBadRequest, "cannot convert the body to json")
-			return
+		// update the product, if it is the version the client expects (of the body, else of If-Match)
+		if body.Version != nil {
+			expectedVersion = *body.Version
 		}
-
-		// serialize the updated product
-		product := internal.Product{
-			Id:           id,
-			Name:         body.Name,
-			Quantity:     body.Quantity,
-			Code_value:   body.Code_value,
-			Is_published: body.Is_published,
-			Expiration:   body.Expiration,
-			Price:        body.Price,
-		}
-
-		// update the product
-		if err := d.sv.Update(&product); err != nil {
-			switch {
-			case errors.Is(err, internal.ErrProductNotFound):
-				response.Error(w, http.StatusNotFound, "product not found")
-			case errors.Is(err, internal.ErrFieldsEmpty):
-				response.Error(w, http.StatusBadRequest, "fields cannot be empty")
-			default:
-				response.Error(w, http.StatusInternalServerError, "internal server error")
-			}
+		if err := d.sv.Update(r.Context(), &product, expectedVersion); err != nil {
+			ResponseError(w, r, err)
 			return
 		}
 
@@ -196,8 +341,10 @@ func (d *DefaultProducts) Update() http.HandlerFunc {
 			Is_published: product.Is_published,
 			Expiration:   product.Expiration,
 			Price:        product.Price,
+			Version:      product.Version,
 		}
 
+		w.Header().Set("ETag", ProductETag(product))
 		response.JSON(w, http.StatusOK, map[string]any{"message": "product updated", "data": data})
 	}
 }
@@ -208,60 +355,44 @@ func (d *DefaultProducts) UpdatePartial() http.HandlerFunc {
 		// get id from path
 		id, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			response.Text(w, http.StatusBadRequest, "invalid id")
+			ResponseError(w, r, ErrInvalidId)
 			return
 		}
 
-		// get the product from the service
-		product, err := d.sv.GetById(id)
-		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErrProductNotFound):
-				response.Text(w, http.StatusNotFound, "product not found")
-			default:
-				response.Text(w, http.StatusInternalServerError, "internal server error")
-			}
+		// get the product from the service, if it is the version the client expects
+		product, err := d.sv.GetById(r.Context(), id)
+		if err = ifMatch(r, product, err); err != nil {
+			ResponseError(w, r, err)
 			return
 		}
 
-		// serialize product to BodyRequestMovieJSON
-		body := BodyRequestProductJSON{
-			Name:         product.Name,
-			Quantity:     product.Quantity,
-			Code_value:   product.Code_value,
-			Is_published: product.Is_published,
-			Expiration:   product.Expiration,
-			Price:        product.Price,
-		}
+		// serialize product to BodyRequestProductJSON
+		body := NewBodyRequestProductJSON(product)
 
 		// get body
-		if err := request.JSON(r, &body); err != nil {
-			response.Text(w, http.StatusBadRequest, "invalid body")
+		verr, err := body.decode(r)
+		if err != nil {
+			ResponseError(w, r, err)
+			return
+		}
+		// - the version expected is the one read, unless the body has one (null or omitted keep the one read)
+		expectedVersion := product.Version
+		if body.Version != nil {
+			expectedVersion = *body.Version
+		}
+
+		// deserialize the updated product, validating the format of the fields
+		product, verrFormat := body.Product(id)
+		verr.Merge(verrFormat)
+		if verr.Err() != nil {
+			d.validationError(w, r, &product, verr)
 			return
 		}
 
-		// serialize the updated product
-
-		product = internal.Product{
-			Id:           id,
-			Name:         body.Name,
-			Quantity:     body.Quantity,
-			Code_value:   body.Code_value,
-			Is_published: body.Is_published,
-			Expiration:   body.Expiration,
-			Price:        body.Price,
-		}
-
-		// update the product
-		if err := d.sv.Update(&product); err != nil {
-			switch {
-			case errors.Is(err, internal.ErrProductNotFound):
-				response.Error(w, http.StatusNotFound, "product not found")
-			case errors.Is(err, internal.ErrFieldsEmpty):
-				response.Error(w, http.StatusBadRequest, "fields cannot be empty")
-			default:
-				response.Error(w, http.StatusInternalServerError, "internal server error")
-			}
+		// update the product, if it is still the version read (or the one of the body)
+		// - so a change made since it was read is not overwritten
+		if err := d.sv.Update(r.Context(), &product, expectedVersion); err != nil {
+			ResponseError(w, r, err)
 			return
 		}
 
@@ -274,30 +405,42 @@ func (d *DefaultProducts) UpdatePartial() http.HandlerFunc {
 			Is_published: product.Is_published,
 			Expiration:   product.Expiration,
 			Price:        product.Price,
+			Version:      product.Version,
 		}
 
+		w.Header().Set("ETag", ProductETag(product))
 		response.JSON(w, http.StatusOK, map[string]any{"message": "product updated", "data": data})
 	}
 }
 
+// validationError writes the format violations of the body together with the rest of violations of the product
+func (d *DefaultProducts) validationError(w http.ResponseWriter, r *http.Request, product *internal.Product, verr *internal.ValidationError) {
+	var other *internal.ValidationError
+	if err := d.sv.Validate(product); errors.As(err, &other) {
+		verr.Merge(other)
+	}
+
+	ResponseError(w, r, verr)
+}
+
 func (d *DefaultProducts) Delete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// request
 		// get id from path
 		id, err := strconv.Atoi(chi.URLParam(r, "id"))
 		if err != nil {
-			response.Error(w, http.StatusBadRequest, "invalid id")
+			ResponseError(w, r, ErrInvalidId)
 			return
 		}
 
-		// delete the product
-		if err := d.sv.Delete(id); err != nil {
-			switch {
-			case errors.Is(err, internal.ErrProductNotFound):
-				response.Text(w, http.StatusNotFound, "product not found")
-			default:
-				response.Text(w, http.StatusInternalServerError, "internal server error")
-			}
+		// delete the product, if it is the version the client expects
+		expectedVersion, err := d.checkIfMatch(r, id)
+		if err != nil {
+			ResponseError(w, r, err)
+			return
+		}
+		if err := d.sv.Delete(r.Context(), id, expectedVersion); err != nil {
+			ResponseError(w, r, err)
 			return
 		}
 
@@ -305,12 +448,3 @@ func (d *DefaultProducts) Delete() http.HandlerFunc {
 		response.JSON(w, http.StatusNoContent, nil)
 	}
 }
-
-func ValidateKeyExistante(body map[string]any, keys ...string) (err error) {
-	for _, key := range keys {
-		if _, ok := body[key]; !ok {
-			return errors.New("key does not exist")
-		}
-	}
-	return
-}
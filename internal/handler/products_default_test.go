@@ -13,6 +13,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
@@ -64,8 +65,8 @@ func TestProductsDefault_GetById(t *testing.T) {
 				Quantity:     10,
 				Code_value:   "123",
 				Is_published: true,
-				Expiration:   "14/05/2024",
-				Price:        100,
+				Expiration:   internal.NewDate(2024, 5, 14),
+				Price:        internal.NewMoney(100, 0),
 			},
 		}
 		rp := repository.NewProductsMap(db)
@@ -88,11 +89,11 @@ func TestProductsDefault_GetById(t *testing.T) {
 			Quantity:     10,
 			Code_value:   "123",
 			Is_published: true,
-			Expiration:   "14/05/2024",
-			Price:        100,
+			Expiration:   internal.NewDate(2024, 5, 14),
+			Price:        internal.NewMoney(100, 0),
 		}
 		expectedCode := http.StatusOK
-		expectedHeader := http.Header{"Content-Type": []string{"application/json"}}
+		expectedHeader := http.Header{"Content-Type": []string{"application/json"}, "Etag": []string{handler.ProductETag(productExpected)}}
 		expectedBody := fmt.Sprintf(`{"message":"product found","data":%s}`, ConvertToJSON(t, productExpected))
 		require.Equal(t, expectedCode, res.Code)
 		require.Equal(t, expectedHeader, res.Header())
@@ -117,8 +118,96 @@ func TestProductsDefault_GetById(t *testing.T) {
 
 		// assert
 		expectedCode := http.StatusNotFound
-		expectedHeader := http.Header{"Content-Type": []string{"application/json"}}
-		expectedBody := fmt.Sprintf(`{"status":"Not Found","message":"product not found"}`)
+		expectedHeader := http.Header{"Content-Type": []string{"application/problem+json"}}
+		expectedBody := `{"type":"urn:go-web:problem:product-not-found","title":"Product not found","status":404,"detail":"product not found","instance":"/products/1"}`
+		require.Equal(t, expectedCode, res.Code)
+		require.Equal(t, expectedHeader, res.Header())
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+
+	t.Run("failure 02 - id is not an integer", func(t *testing.T) {
+		// arrange
+		// - repository
+		rp := repository.NewProductsMap(nil)
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		getById := hd.GetById()
+
+		// act
+		req := NewRequest("GET", "/products/abc", nil, map[string]string{"id": "abc"}, nil)
+		res := httptest.NewRecorder()
+		getById(res, req)
+
+		// assert
+		expectedCode := http.StatusBadRequest
+		expectedHeader := http.Header{"Content-Type": []string{"application/problem+json"}}
+		expectedBody := `{"type":"urn:go-web:problem:invalid-id","title":"Invalid id","status":400,"detail":"id must be an integer","instance":"/products/abc"}`
+		require.Equal(t, expectedCode, res.Code)
+		require.Equal(t, expectedHeader, res.Header())
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+}
+
+func TestProductsDefault_GetByCode(t *testing.T) {
+	t.Run("success 01 - should return a product", func(t *testing.T) {
+		// arrange
+		// - repository
+		db := []internal.Product{
+			{
+				Id:           1,
+				Name:         "product 1",
+				Quantity:     10,
+				Code_value:   "123",
+				Is_published: true,
+				Expiration:   internal.NewDate(2024, 5, 14),
+				Price:        internal.NewMoney(100, 0),
+			},
+		}
+		rp := repository.NewProductsMap(db)
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		getByCode := hd.GetByCode()
+
+		// act
+		req := NewRequest("GET", "/products/code/123", nil, map[string]string{"code": "123"}, nil)
+		res := httptest.NewRecorder()
+		getByCode(res, req)
+
+		// assert
+		expectedCode := http.StatusOK
+		expectedHeader := http.Header{"Content-Type": []string{"application/json"}, "Etag": []string{handler.ProductETag(db[0])}}
+		expectedBody := fmt.Sprintf(`{"message":"product found","data":%s}`, ConvertToJSON(t, db[0]))
+		require.Equal(t, expectedCode, res.Code)
+		require.Equal(t, expectedHeader, res.Header())
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+
+	t.Run("failure 01 - product not found", func(t *testing.T) {
+		// arrange
+		// - repository
+		rp := repository.NewProductsMap(nil)
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		getByCode := hd.GetByCode()
+
+		// act
+		req := NewRequest("GET", "/products/code/123", nil, map[string]string{"code": "123"}, nil)
+		res := httptest.NewRecorder()
+		getByCode(res, req)
+
+		// assert
+		expectedCode := http.StatusNotFound
+		expectedHeader := http.Header{"Content-Type": []string{"application/problem+json"}}
+		expectedBody := `{"type":"urn:go-web:problem:product-not-found","title":"Product not found","status":404,"detail":"product not found","instance":"/products/code/123"}`
 		require.Equal(t, expectedCode, res.Code)
 		require.Equal(t, expectedHeader, res.Header())
 		require.JSONEq(t, expectedBody, res.Body.String())
@@ -136,8 +225,8 @@ func TestProductsDefault_GetAll(t *testing.T) {
 				Quantity:     10,
 				Code_value:   "123",
 				Is_published: true,
-				Expiration:   "14/05/2024",
-				Price:        100,
+				Expiration:   internal.NewDate(2024, 5, 14),
+				Price:        internal.NewMoney(100, 0),
 			},
 			{
 				Id:           2,
@@ -145,8 +234,8 @@ func TestProductsDefault_GetAll(t *testing.T) {
 				Quantity:     20,
 				Code_value:   "456",
 				Is_published: true,
-				Expiration:   "14/05/2024",
-				Price:        200,
+				Expiration:   internal.NewDate(2024, 5, 14),
+				Price:        internal.NewMoney(200, 0),
 			},
 		}
 		rp := repository.NewProductsMap(db)
@@ -170,8 +259,8 @@ func TestProductsDefault_GetAll(t *testing.T) {
 				Quantity:     10,
 				Code_value:   "123",
 				Is_published: true,
-				Expiration:   "14/05/2024",
-				Price:        100,
+				Expiration:   internal.NewDate(2024, 5, 14),
+				Price:        internal.NewMoney(100, 0),
 			},
 			{
 				Id:           2,
@@ -179,17 +268,155 @@ func TestProductsDefault_GetAll(t *testing.T) {
 				Quantity:     20,
 				Code_value:   "456",
 				Is_published: true,
-				Expiration:   "14/05/2024",
-				Price:        200,
+				Expiration:   internal.NewDate(2024, 5, 14),
+				Price:        internal.NewMoney(200, 0),
 			},
 		}
 		expectedCode := http.StatusOK
-		expectedHeader := http.Header{"Content-Type": []string{"application/json"}}
-		expectedBody := fmt.Sprintf(`{"message":"products found","data":%s}`, ConvertToJSON(t, productsExpected))
+		expectedHeader := http.Header{"Content-Type": []string{"application/json"}, "Etag": []string{`W/"65342153f9a8550c26b55c4b50eb6a54"`}}
+		expectedBody := fmt.Sprintf(`{"message":"products found","data":%s,"total":2,"limit":0,"offset":0}`, ConvertToJSON(t, productsExpected))
 		require.Equal(t, expectedCode, res.Code)
 		require.Equal(t, expectedHeader, res.Header())
 		require.JSONEq(t, expectedBody, res.Body.String())
 	})
+
+	t.Run("success 02 - should filter, sort and paginate the products", func(t *testing.T) {
+		// arrange
+		// - repository
+		db := []internal.Product{
+			{Id: 1, Name: "Wine - Red", Quantity: 10, Code_value: "1", Is_published: true, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(300, 0)},
+			{Id: 2, Name: "Wine - White", Quantity: 20, Code_value: "2", Is_published: true, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(100, 0)},
+			{Id: 3, Name: "Wine - Rose", Quantity: 30, Code_value: "3", Is_published: false, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(200, 0)},
+			{Id: 4, Name: "Cookie", Quantity: 40, Code_value: "4", Is_published: true, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(400, 0)},
+		}
+		rp := repository.NewProductsMap(db)
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		getAll := hd.GetAll()
+
+		// act
+		query := map[string]string{"name": "wine", "is_published": "true", "sort_by": "price", "order": "desc", "limit": "1", "offset": "1"}
+		req := NewRequest("GET", "/products", nil, nil, query)
+		res := httptest.NewRecorder()
+		getAll(res, req)
+
+		// assert
+		expectedCode := http.StatusOK
+		expectedBody := fmt.Sprintf(`{"message":"products found","data":%s,"total":2,"limit":1,"offset":1}`, ConvertToJSON(t, []internal.Product{db[1]}))
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+
+	t.Run("failure 01 - invalid query", func(t *testing.T) {
+		// arrange
+		// - repository
+		rp := repository.NewProductsMap(nil)
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		getAll := hd.GetAll()
+
+		// act
+		req := NewRequest("GET", "/products", nil, nil, map[string]string{"sort_by": "color"})
+		res := httptest.NewRecorder()
+		getAll(res, req)
+
+		// assert
+		expectedCode := http.StatusBadRequest
+		expectedBody := `{"type":"urn:go-web:problem:invalid-query","title":"Invalid query","status":400,"detail":"invalid query: cannot sort by color","instance":"/products"}`
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+}
+
+func TestProductsDefault_Search(t *testing.T) {
+	t.Run("success 01 - should return the products that match all the predicates", func(t *testing.T) {
+		// arrange
+		// - repository
+		soon := internal.NewDate(time.Now().AddDate(0, 0, 3).Date())
+		later := internal.NewDate(time.Now().AddDate(0, 0, 30).Date())
+		db := []internal.Product{
+			{Id: 1, Name: "product 1", Quantity: 10, Code_value: "1", Is_published: true, Expiration: soon, Price: internal.NewMoney(300, 0)},
+			{Id: 2, Name: "product 2", Quantity: 20, Code_value: "2", Is_published: true, Expiration: later, Price: internal.NewMoney(300, 0)},
+			{Id: 3, Name: "product 3", Quantity: 30, Code_value: "3", Is_published: false, Expiration: soon, Price: internal.NewMoney(300, 0)},
+			{Id: 4, Name: "product 4", Quantity: 40, Code_value: "4", Is_published: true, Expiration: soon, Price: internal.NewMoney(100, 0)},
+		}
+		rp := repository.NewProductsMap(db)
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		search := hd.Search()
+
+		// act
+		query := map[string]string{"priceGt": "200", "expiringWithin": "7", "publishedOnly": "true"}
+		req := NewRequest("GET", "/products/search", nil, nil, query)
+		res := httptest.NewRecorder()
+		search(res, req)
+
+		// assert
+		expectedCode := http.StatusOK
+		expectedBody := fmt.Sprintf(`{"message":"products found","data":%s}`, ConvertToJSON(t, []internal.Product{db[0]}))
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+
+	t.Run("success 02 - should rank the products matching the text", func(t *testing.T) {
+		// arrange
+		// - repository
+		db := []internal.Product{
+			{Id: 1, Name: "Wine - White", Quantity: 10, Code_value: "1", Is_published: true, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(300, 0)},
+			{Id: 2, Name: "Wine - Red Oakridge Merlot", Quantity: 20, Code_value: "2", Is_published: true, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(300, 0)},
+			{Id: 3, Name: "Cookie - Oatmeal", Quantity: 30, Code_value: "3", Is_published: true, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(300, 0)},
+		}
+		rp := repository.NewProductsMap(db)
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		search := hd.Search()
+
+		// act
+		req := NewRequest("GET", "/products/search", nil, nil, map[string]string{"q": "red wine"})
+		res := httptest.NewRecorder()
+		search(res, req)
+
+		// assert
+		expectedCode := http.StatusOK
+		expectedBody := fmt.Sprintf(`{"message":"products found","data":%s}`, ConvertToJSON(t, []internal.Product{db[1], db[0]}))
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+
+	t.Run("failure 01 - invalid predicate", func(t *testing.T) {
+		// arrange
+		// - repository
+		rp := repository.NewProductsMap(nil)
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		search := hd.Search()
+
+		// act
+		req := NewRequest("GET", "/products/search", nil, nil, map[string]string{"priceGt": "cheap"})
+		res := httptest.NewRecorder()
+		search(res, req)
+
+		// assert
+		expectedCode := http.StatusBadRequest
+		expectedBody := `{"type":"urn:go-web:problem:invalid-query","title":"Invalid query","status":400,"detail":"invalid query: priceGt is not valid","instance":"/products/search"}`
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
 }
 
 func TestProductsDefault_Post(t *testing.T) {
@@ -213,12 +440,175 @@ func TestProductsDefault_Post(t *testing.T) {
 
 		// assert
 		expectedCode := http.StatusCreated
-		expectedHeader := http.Header{"Content-Type": []string{"application/json"}}
-		expectedBody := fmt.Sprintf(`{"data":{"Id":1,"Name":"product 1","Quantity":1,"Code_value":"code1","Is_published":true,"Expiration":"14/05/2024","Price":1.1},"message":"product created"}`)
+		productExpected := internal.Product{Id: 1, Name: "product 1", Quantity: 1, Code_value: "code1", Is_published: true, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(1, 10), Version: 1}
+		expectedHeader := http.Header{"Content-Type": []string{"application/json"}, "Etag": []string{handler.ProductETag(productExpected)}}
+		expectedBody := fmt.Sprintf(`{"data":{"Id":1,"Name":"product 1","Quantity":1,"Code_value":"code1","Is_published":true,"Expiration":"14/05/2024","Price":1.1,"Version":1},"message":"product created"}`)
 		require.Equal(t, expectedCode, res.Code)
 		require.Equal(t, expectedHeader, res.Header())
 		require.JSONEq(t, expectedBody, res.Body.String())
 	})
+
+	t.Run("success 02 - should create a product without stock", func(t *testing.T) {
+		// arrange
+		// - repository
+		rp := repository.NewProductsMap([]internal.Product{})
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		create := hd.Create()
+
+		// act
+		body := `{"name": "product 1", "quantity": 0, "code_value": "code1", "is_published": false, "expiration": "2024-05-14", "price": 0}`
+		req := NewRequest("POST", "/products", strings.NewReader(body), nil, nil)
+		res := httptest.NewRecorder()
+		create(res, req)
+
+		// assert
+		expectedCode := http.StatusCreated
+		expectedBody := `{"data":{"Id":1,"Name":"product 1","Quantity":0,"Code_value":"code1","Is_published":false,"Expiration":"14/05/2024","Price":0,"Version":1},"message":"product created"}`
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+
+	t.Run("failure 01 - should return all the violations", func(t *testing.T) {
+		// arrange
+		// - repository
+		rp := repository.NewProductsMap([]internal.Product{})
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		create := hd.Create()
+
+		// act
+		body := `{"name": "", "quantity": -1, "code_value": "code1", "expiration": "31/02/2024", "price": "cheap"}`
+		req := NewRequest("POST", "/products", strings.NewReader(body), nil, nil)
+		res := httptest.NewRecorder()
+		create(res, req)
+
+		// assert
+		expectedCode := http.StatusUnprocessableEntity
+		expectedBody := `{"type":"urn:go-web:problem:validation","title":"Product is not valid","status":422,"instance":"/products","errors":[
+			{"field":"expiration","rule":"format","message":"expiration must be a valid date (dd/mm/yyyy or yyyy-mm-dd)"},
+			{"field":"price","rule":"format","message":"price must be a valid amount"},
+			{"field":"name","rule":"required","message":"name is required"},
+			{"field":"quantity","rule":"range","message":"quantity cannot be negative"}
+		]}`
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+
+	t.Run("failure 02 - code value must be unique", func(t *testing.T) {
+		// arrange
+		// - repository
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Name: "product 1", Code_value: "code1"}})
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		create := hd.Create()
+
+		// act
+		body := `{"name": "product 2", "quantity": 1, "code_value": "code1", "expiration": "14/05/2024", "price": 1}`
+		req := NewRequest("POST", "/products", strings.NewReader(body), nil, nil)
+		res := httptest.NewRecorder()
+		create(res, req)
+
+		// assert
+		expectedCode := http.StatusUnprocessableEntity
+		expectedBody := `{"type":"urn:go-web:problem:validation","title":"Product is not valid","status":422,"instance":"/products","errors":[
+			{"field":"code_value","rule":"unique","message":"code value must be unique"}
+		]}`
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+}
+
+func TestProductsDefault_Put(t *testing.T) {
+	t.Run("success 01 - should update a product without stock nor price", func(t *testing.T) {
+		// arrange
+		// - repository
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Name: "product 1", Quantity: 5, Code_value: "code1", Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(1, 0), Version: 1}})
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		update := hd.Update()
+
+		// act
+		body := `{"name": "product 2", "quantity": 0, "code_value": "code1", "is_published": false, "expiration": "14/05/2024", "price": 0}`
+		req := NewRequest("PUT", "/products/1", strings.NewReader(body), map[string]string{"id": "1"}, nil)
+		res := httptest.NewRecorder()
+		update(res, req)
+
+		// assert
+		expectedCode := http.StatusOK
+		expectedBody := `{"data":{"Id":1,"Name":"product 2","Quantity":0,"Code_value":"code1","Is_published":false,"Expiration":"14/05/2024","Price":0,"Version":2},"message":"product updated"}`
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+
+	t.Run("failure 01 - should report the missing fields of the full representation as violations", func(t *testing.T) {
+		// arrange
+		// - repository
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Name: "product 1", Code_value: "code1", Expiration: internal.NewDate(2024, 5, 14)}})
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		update := hd.Update()
+
+		// act
+		body := `{"quantity": 1, "price": 1}`
+		req := NewRequest("PUT", "/products/1", strings.NewReader(body), map[string]string{"id": "1"}, nil)
+		res := httptest.NewRecorder()
+		update(res, req)
+
+		// assert
+		expectedCode := http.StatusUnprocessableEntity
+		expectedBody := `{"type":"urn:go-web:problem:validation","title":"Product is not valid","status":422,"instance":"/products/1","errors":[
+			{"field":"name","rule":"required","message":"name is required"},
+			{"field":"code_value","rule":"required","message":"code value is required"},
+			{"field":"is_published","rule":"required","message":"is published is required"},
+			{"field":"expiration","rule":"required","message":"expiration is required"}
+		]}`
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+
+	t.Run("failure 02 - should report the fields of another type as violations", func(t *testing.T) {
+		// arrange
+		// - repository
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Name: "product 1", Code_value: "code1", Expiration: internal.NewDate(2024, 5, 14)}})
+		// - service
+		sv := service.NewProductDefault(rp)
+		// - handler
+		hd := handler.NewDefaultProducts(sv)
+		// - handler function
+		update := hd.Update()
+
+		// act
+		body := `{"name": "product 1", "quantity": "abc", "code_value": "code1", "is_published": "yes", "expiration": 20240514, "price": 1}`
+		req := NewRequest("PUT", "/products/1", strings.NewReader(body), map[string]string{"id": "1"}, nil)
+		res := httptest.NewRecorder()
+		update(res, req)
+
+		// assert
+		expectedCode := http.StatusUnprocessableEntity
+		expectedBody := `{"type":"urn:go-web:problem:validation","title":"Product is not valid","status":422,"instance":"/products/1","errors":[
+			{"field":"quantity","rule":"format","message":"quantity must be an integer"},
+			{"field":"is_published","rule":"format","message":"is published must be a boolean"},
+			{"field":"expiration","rule":"format","message":"expiration must be a valid date (dd/mm/yyyy or yyyy-mm-dd)"}
+		]}`
+		require.Equal(t, expectedCode, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
 }
 
 func TestProductsDefault_Delete(t *testing.T) {
@@ -232,8 +622,8 @@ func TestProductsDefault_Delete(t *testing.T) {
 				Quantity:     10,
 				Code_value:   "123",
 				Is_published: true,
-				Expiration:   "14/05/2024",
-				Price:        100,
+				Expiration:   internal.NewDate(2024, 5, 14),
+				Price:        internal.NewMoney(100, 0),
 			},
 		}
 		// - repository
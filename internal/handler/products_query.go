@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"app/internal"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// in this file i handle the query parameters used to filter, sort and paginate the products
+
+// ParseProductQuery builds a product query from the query parameters of the url
+// - name, is_published, price_min, price_max, quantity_min, quantity_max
+// - expiration_before, expiration_after (dd/mm/yyyy or yyyy-mm-dd)
+// - sort_by, order (asc or desc)
+// - limit, offset
+func ParseProductQuery(values url.Values) (query internal.ProductQuery, err error) {
+	query.Name = values.Get("name")
+
+	if query.IsPublished, err = parseOptional(values, "is_published", strconv.ParseBool); err != nil {
+		return
+	}
+	if query.PriceMin, err = parseOptional(values, "price_min", internal.ParseMoney); err != nil {
+		return
+	}
+	if query.PriceMax, err = parseOptional(values, "price_max", internal.ParseMoney); err != nil {
+		return
+	}
+	if query.QuantityMin, err = parseOptional(values, "quantity_min", strconv.Atoi); err != nil {
+		return
+	}
+	if query.QuantityMax, err = parseOptional(values, "quantity_max", strconv.Atoi); err != nil {
+		return
+	}
+	if query.ExpirationBefore, err = parseOptional(values, "expiration_before", internal.ParseDate); err != nil {
+		return
+	}
+	if query.ExpirationAfter, err = parseOptional(values, "expiration_after", internal.ParseDate); err != nil {
+		return
+	}
+
+	// sort
+	query.SortBy = values.Get("sort_by")
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.SortDesc = true
+	default:
+		err = fmt.Errorf("%w: order must be asc or desc", internal.ErrInvalidQuery)
+		return
+	}
+
+	// pagination
+	if limit, errParse := parseOptional(values, "limit", strconv.Atoi); errParse != nil {
+		err = errParse
+		return
+	} else if limit != nil {
+		query.Limit = *limit
+	}
+	if offset, errParse := parseOptional(values, "offset", strconv.Atoi); errParse != nil {
+		err = errParse
+		return
+	} else if offset != nil {
+		query.Offset = *offset
+	}
+
+	return
+}
+
+// ParseProductSearch builds a product search from the query parameters of the url
+// - q, priceGt, priceLt, expiringWithin (days), publishedOnly
+func ParseProductSearch(values url.Values) (search internal.ProductSearch, err error) {
+	search.Q = values.Get("q")
+
+	if search.PriceGt, err = parseOptional(values, "priceGt", internal.ParseMoney); err != nil {
+		return
+	}
+	if search.PriceLt, err = parseOptional(values, "priceLt", internal.ParseMoney); err != nil {
+		return
+	}
+	if search.ExpiringWithinDays, err = parseOptional(values, "expiringWithin", strconv.Atoi); err != nil {
+		return
+	}
+	publishedOnly, err := parseOptional(values, "publishedOnly", strconv.ParseBool)
+	if err != nil {
+		return
+	}
+	search.PublishedOnly = publishedOnly != nil && *publishedOnly
+
+	return
+}
+
+// parseOptional parses a query parameter if it is present
+func parseOptional[T any](values url.Values, key string, parse func(string) (T, error)) (value *T, err error) {
+	raw := values.Get(key)
+	if raw == "" {
+		return
+	}
+
+	v, err := parse(raw)
+	if err != nil {
+		err = fmt.Errorf("%w: %s is not valid", internal.ErrInvalidQuery, key)
+		return
+	}
+	return &v, nil
+}
@@ -0,0 +1,308 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// in this file i handle a minimal registry of metrics written in the prometheus text exposition format
+// - https://prometheus.io/docs/instrumenting/exposition_formats/
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds (in seconds) of the buckets of a latency histogram
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metric is a metric that writes its samples
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry creates a new Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Registry is a set of metrics, written in the order they were registered
+type Registry struct {
+	// mu protects metrics
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// register adds a metric to the registry
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes all the metrics in the text exposition format
+func (r *Registry) WriteTo(w io.Writer) (n int64, err error) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{Writer: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err = bw.Flush()
+	return cw.n, err
+}
+
+// Handler returns a handler that responds all the metrics
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		w.WriteHeader(http.StatusOK)
+		r.WriteTo(w)
+	}
+}
+
+// CounterVec is a counter with labels
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+	// mu protects values
+	mu sync.Mutex
+	// values are the counts by the joined values of the labels
+	values map[string]*series
+}
+
+// series is the state of a combination of values of the labels
+type series struct {
+	labelValues []string
+	// value is the count of a counter
+	value float64
+	// buckets, sum and count are the state of a histogram
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewCounterVec registers a new CounterVec.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]*series),
+	}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the counter of the values of the labels
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (non negative) to the counter of the values of the labels
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := c.values[key]
+	if !ok {
+		s = &series{labelValues: labelValues}
+		c.values[key] = s
+	}
+	s.value += v
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for _, s := range sortedSeries(c.values) {
+		writeSample(w, c.name, c.labels, s.labelValues, "", "", s.value)
+	}
+}
+
+// HistogramVec is a histogram with labels
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	// mu protects values
+	mu sync.Mutex
+	// values are the observations by the joined values of the labels
+	values map[string]*series
+}
+
+// NewHistogramVec registers a new HistogramVec.
+// - buckets are the upper bounds of the buckets, in increasing order (the +Inf bucket is implicit)
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  make(map[string]*series),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe adds an observation to the histogram of the values of the labels
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := h.values[key]
+	if !ok {
+		s = &series{labelValues: labelValues, buckets: make([]uint64, len(h.buckets))}
+		h.values[key] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.buckets[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, s := range sortedSeries(h.values) {
+		for i, upper := range h.buckets {
+			writeSample(w, h.name+"_bucket", h.labels, s.labelValues, "le", formatFloat(upper), float64(s.buckets[i]))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(w, h.name+"_sum", h.labels, s.labelValues, "", "", s.sum)
+		writeSample(w, h.name+"_count", h.labels, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// Gauge is the value of a gauge collected on a scrape
+type Gauge struct {
+	Name  string
+	Help  string
+	Value float64
+}
+
+// gaugeFuncs are gauges collected together on every scrape
+type gaugeFuncs struct {
+	collect func() []Gauge
+}
+
+// NewGaugeFuncs registers gauges whose values are collected together by collect on every scrape
+// - e.g. several figures computed in a single pass over the data
+func (r *Registry) NewGaugeFuncs(collect func() []Gauge) {
+	r.register(&gaugeFuncs{collect: collect})
+}
+
+func (g *gaugeFuncs) write(w *bufio.Writer) {
+	for _, gauge := range g.collect() {
+		writeHeader(w, gauge.Name, gauge.Help, "gauge")
+		writeSample(w, gauge.Name, nil, nil, "", "", gauge.Value)
+	}
+}
+
+// sortedSeries returns the series ordered by the values of their labels, so the output is stable
+func sortedSeries(values map[string]*series) []*series {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := make([]*series, len(keys))
+	for i, key := range keys {
+		list[i] = values[key]
+	}
+	return list
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// writeSample writes a sample line, with an optional extra label (e.g. le)
+func writeSample(w *bufio.Writer, name string, labels, labelValues []string, extraLabel, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			value := ""
+			if i < len(labelValues) {
+				value = labelValues[i]
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", label, escapeLabel(value))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// formatFloat formats a value as prometheus expects it
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabel escapes the backslashes, quotes and line feeds of a label value
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// escapeHelp escapes the backslashes and line feeds of a help text
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// countingWriter is a writer that counts the bytes written
+type countingWriter struct {
+	io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = w.Writer.Write(p)
+	w.n += int64(n)
+	return
+}
@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"app/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	t.Run("success 01 - should write the metrics in the text exposition format", func(t *testing.T) {
+		// arrange
+		reg := metrics.NewRegistry()
+		requests := reg.NewCounterVec("http_requests_total", "Number of http requests.", "route", "status")
+		duration := reg.NewHistogramVec("http_request_duration_seconds", "Latency.", []float64{0.1, 1}, "route")
+		reg.NewGaugeFuncs(func() []metrics.Gauge {
+			return []metrics.Gauge{{Name: "products", Help: "Number of products.", Value: 3}}
+		})
+		requests.Inc("/products/{id}", "200")
+		requests.Inc("/products/{id}", "200")
+		requests.Inc(`/a"b`, "404")
+		duration.Observe(0.05, "/products")
+		duration.Observe(0.5, "/products")
+
+		// act
+		var out strings.Builder
+		_, err := reg.WriteTo(&out)
+
+		// assert
+		expected := `# HELP http_requests_total Number of http requests.
+# TYPE http_requests_total counter
+http_requests_total{route="/a\"b",status="404"} 1
+http_requests_total{route="/products/{id}",status="200"} 2
+# HELP http_request_duration_seconds Latency.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{route="/products",le="0.1"} 1
+http_request_duration_seconds_bucket{route="/products",le="1"} 2
+http_request_duration_seconds_bucket{route="/products",le="+Inf"} 2
+http_request_duration_seconds_sum{route="/products"} 0.55
+http_request_duration_seconds_count{route="/products"} 2
+# HELP products Number of products.
+# TYPE products gauge
+products 3
+`
+		require.NoError(t, err)
+		require.Equal(t, expected, out.String())
+	})
+
+	t.Run("success 02 - handler responds the content type of the format", func(t *testing.T) {
+		// arrange
+		reg := metrics.NewRegistry()
+
+		// act
+		res := httptest.NewRecorder()
+		reg.Handler()(res, httptest.NewRequest("GET", "/metrics", nil))
+
+		// assert
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Equal(t, metrics.ContentType, res.Header().Get("Content-Type"))
+	})
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"app/internal"
+	"app/platform/web/response"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// scopes of the api keys
+const (
+	ScopeProductsRead   = "products:read"
+	ScopeProductsWrite  = "products:write"
+	ScopeProductsDelete = "products:delete"
+)
+
+// errors
+var (
+	ErrAPIKeysFile = errors.New("invalid api keys file")
+)
+
+// APIKey is a struct that represents an entry of the api keys file
+// - several keys can share a name, so a new key can be added before the old one expires
+type APIKey struct {
+	// Name identifies the client of the key
+	Name string `json:"name"`
+	// KeySHA256 is the hex sha256 of the key (preferred, so the file does not hold secrets)
+	KeySHA256 string `json:"key_sha256"`
+	// Key is the key in plain text (used when KeySHA256 is empty)
+	Key string `json:"key"`
+	// Scopes are the permissions granted to the key
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is the moment from which the key is rejected (zero means it does not expire)
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// apiKey is an api key ready to be compared
+type apiKey struct {
+	name      string
+	digest    []byte
+	scopes    []string
+	expiresAt time.Time
+}
+
+// APIKeys is a middleware that authenticates the request with one of several scoped api keys.
+type APIKeys struct {
+	keys []apiKey
+	// now returns the current time
+	now func() time.Time
+}
+
+// NewAPIKeys creates a new APIKeys.
+func NewAPIKeys(keys []APIKey) (a *APIKeys, err error) {
+	a = &APIKeys{now: time.Now}
+	for i, k := range keys {
+		key := apiKey{name: k.Name, scopes: k.Scopes, expiresAt: k.ExpiresAt}
+		switch {
+		case k.KeySHA256 != "":
+			key.digest, err = hex.DecodeString(k.KeySHA256)
+			if err != nil || len(key.digest) != sha256.Size {
+				return nil, fmt.Errorf("%w: key %d (%s): key_sha256 must be a hex sha256", ErrAPIKeysFile, i, k.Name)
+			}
+		case k.Key != "":
+			digest := sha256.Sum256([]byte(k.Key))
+			key.digest = digest[:]
+		default:
+			return nil, fmt.Errorf("%w: key %d (%s): key or key_sha256 is required", ErrAPIKeysFile, i, k.Name)
+		}
+		if k.Name == "" {
+			return nil, fmt.Errorf("%w: key %d: name is required", ErrAPIKeysFile, i)
+		}
+		a.keys = append(a.keys, key)
+	}
+	return
+}
+
+// LoadAPIKeys creates a new APIKeys with the keys of a json file.
+func LoadAPIKeys(path string) (a *APIKeys, err error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAPIKeysFile, err)
+	}
+
+	var keys []APIKey
+	if err = json.Unmarshal(bytes, &keys); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrAPIKeysFile, path, err)
+	}
+
+	return NewAPIKeys(keys)
+}
+
+// RequireScopes returns a middleware that lets through the requests whose api key has all the scopes.
+// - the principal of the key is stored in the context of the request
+func (a *APIKeys) RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// logic before
+			key, ok := a.find(bearerToken(r))
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="products"`)
+				response.Error(w, http.StatusUnauthorized, "invalid or expired api key")
+				return
+			}
+
+			principal := internal.Principal{Subject: key.name, Scopes: key.scopes}
+			for _, scope := range scopes {
+				if !principal.HasScope(scope) {
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="products", error="insufficient_scope", scope=%q`, strings.Join(scopes, " ")))
+					response.Errorf(w, http.StatusForbidden, "api key %s lacks the scope %s", key.name, scope)
+					return
+				}
+			}
+
+			// call next handler
+			next.ServeHTTP(w, r.WithContext(internal.ContextWithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// find returns the key that matches a token and has not expired
+// - every key is compared (in constant time), so the time spent does not reveal which one matched
+func (a *APIKeys) find(token string) (key apiKey, ok bool) {
+	if token == "" {
+		return
+	}
+
+	digest := sha256.Sum256([]byte(token))
+	now := a.now()
+	for _, k := range a.keys {
+		match := subtle.ConstantTimeCompare(digest[:], k.digest) == 1
+		if match && !ok && (k.expiresAt.IsZero() || now.Before(k.expiresAt)) {
+			key, ok = k, true
+		}
+	}
+	return
+}
+
+// bearerToken returns the token of an "Authorization: Bearer <token>" header (empty if the scheme is not bearer)
+func bearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(strings.TrimSpace(r.Header.Get("Authorization")), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
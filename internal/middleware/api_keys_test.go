@@ -0,0 +1,114 @@
+package middleware_test
+
+import (
+	"app/internal"
+	"app/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys_RequireScopes(t *testing.T) {
+	// arrange
+	keys, err := middleware.NewAPIKeys([]middleware.APIKey{
+		{Name: "warehouse", Key: "old-key", Scopes: []string{middleware.ScopeProductsWrite}, ExpiresAt: time.Now().Add(-time.Hour)},
+		{Name: "warehouse", Key: "new-key", Scopes: []string{middleware.ScopeProductsWrite}},
+		{Name: "rotating", Key: "key-1", Scopes: []string{middleware.ScopeProductsWrite}, ExpiresAt: time.Now().Add(time.Hour)},
+		{Name: "rotating", Key: "key-2", Scopes: []string{middleware.ScopeProductsWrite}},
+	})
+	require.NoError(t, err)
+	var principal internal.Principal
+	handler := keys.RequireScopes(middleware.ScopeProductsWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = internal.PrincipalFromContext(r.Context())
+	}))
+
+	t.Run("success 01 - should let through a key with the scope", func(t *testing.T) {
+		// act
+		req := httptest.NewRequest("POST", "/products", nil)
+		req.Header.Set("Authorization", "Bearer new-key")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Equal(t, "warehouse", principal.Subject)
+	})
+
+	t.Run("success 02 - both keys are valid while rotating", func(t *testing.T) {
+		for _, key := range []string{"key-1", "key-2"} {
+			// act
+			req := httptest.NewRequest("POST", "/products", nil)
+			req.Header.Set("Authorization", "Bearer "+key)
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+
+			// assert
+			require.Equal(t, http.StatusOK, res.Code)
+		}
+	})
+
+	t.Run("failure 01 - expired key", func(t *testing.T) {
+		// act
+		req := httptest.NewRequest("POST", "/products", nil)
+		req.Header.Set("Authorization", "Bearer old-key")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		require.Equal(t, http.StatusUnauthorized, res.Code)
+		require.NotEmpty(t, res.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("failure 02 - key without the scope", func(t *testing.T) {
+		// arrange
+		handler := keys.RequireScopes(middleware.ScopeProductsDelete)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		// act
+		req := httptest.NewRequest("DELETE", "/products/1", nil)
+		req.Header.Set("Authorization", "Bearer new-key")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		expectedBody := `{"status":"Forbidden","message":"api key warehouse lacks the scope products:delete"}`
+		require.Equal(t, http.StatusForbidden, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	t.Run("success 01 - should load the keys of the file", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "keys.json")
+		content := `[{"name":"admin","key_sha256":"8d969eef6ecad3c29a3a629280e686cf0c3f5d5a86aff3ca12020c923adc6c92","scopes":["products:delete"]}]`
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+		// act
+		keys, err := middleware.LoadAPIKeys(path)
+
+		// assert
+		require.NoError(t, err)
+		req := httptest.NewRequest("DELETE", "/products/1", nil)
+		req.Header.Set("Authorization", "Bearer 123456")
+		res := httptest.NewRecorder()
+		keys.RequireScopes(middleware.ScopeProductsDelete)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(res, req)
+		require.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("failure 01 - key without hash nor key", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "keys.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[{"name":"admin"}]`), 0600))
+
+		// act
+		_, err := middleware.LoadAPIKeys(path)
+
+		// assert
+		require.ErrorIs(t, err, middleware.ErrAPIKeysFile)
+	})
+}
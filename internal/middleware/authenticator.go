@@ -1,29 +1,54 @@
 package middleware
 
 import (
+	"app/platform/web/response"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
 	"net/http"
 )
 
+// errors
+var (
+	ErrEmptyToken = errors.New("the token cannot be empty (set API_TOKEN or allow an empty token explicitly)")
+)
+
 // Authenticator is a middleware that authenticates the request using a token.
 type Authenticator struct {
-	token string
+	// digest is the sha256 of the token, so every comparison takes the same time whatever its length
+	digest [sha256.Size]byte
+	// disabled lets through every request (an empty token explicitly allowed)
+	disabled bool
 }
 
 // NewAuthenticator creates a new Authenticator.
-func NewAuthenticator(token string) *Authenticator {
-	return &Authenticator{
-		token: token,
+// - an empty token is refused unless allowEmpty is set, in which case the authentication is disabled
+func NewAuthenticator(token string, allowEmpty bool) (a *Authenticator, err error) {
+	if token == "" {
+		if !allowEmpty {
+			return nil, ErrEmptyToken
+		}
+		return &Authenticator{disabled: true}, nil
 	}
+
+	return &Authenticator{
+		digest: sha256.Sum256([]byte(token)),
+	}, nil
 }
 
 // ValidateToken checks if the person is authorized to access the resource.
+// - the token is sent as "Authorization: Bearer <token>"
 func (a *Authenticator) ValidateToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// logic before
-		token := r.Header.Get("Authorization")
-		if token != a.token {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
+		if !a.disabled {
+			token := bearerToken(r)
+			digest := sha256.Sum256([]byte(token))
+			if token == "" || subtle.ConstantTimeCompare(digest[:], a.digest[:]) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="products"`)
+				response.Error(w, http.StatusUnauthorized, "invalid or missing bearer token")
+				return
+			}
 		}
 
 		// call next handler
@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"app/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	t.Run("failure 01 - empty token not allowed", func(t *testing.T) {
+		// act
+		_, err := middleware.NewAuthenticator("", false)
+
+		// assert
+		require.ErrorIs(t, err, middleware.ErrEmptyToken)
+	})
+}
+
+func TestAuthenticator_ValidateToken(t *testing.T) {
+	// arrange
+	auth, err := middleware.NewAuthenticator("123456", false)
+	require.NoError(t, err)
+	handler := auth.ValidateToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	t.Run("success 01 - should let through the bearer token", func(t *testing.T) {
+		// act
+		req := httptest.NewRequest("POST", "/products", nil)
+		req.Header.Set("Authorization", "Bearer 123456")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, res.Code)
+	})
+
+	t.Run("failure 01 - token without the bearer scheme", func(t *testing.T) {
+		// act
+		req := httptest.NewRequest("POST", "/products", nil)
+		req.Header.Set("Authorization", "123456")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		expectedBody := `{"status":"Unauthorized","message":"invalid or missing bearer token"}`
+		expectedHeaders := http.Header{
+			"Content-Type":     []string{"application/json"},
+			"Www-Authenticate": []string{`Bearer realm="products"`},
+		}
+		require.Equal(t, http.StatusUnauthorized, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+		require.Equal(t, expectedHeaders, res.Header())
+	})
+
+	t.Run("failure 02 - missing header", func(t *testing.T) {
+		// act
+		req := httptest.NewRequest("POST", "/products", nil)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		require.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+}
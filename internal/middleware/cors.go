@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigCORS is a struct that represents the configuration of the cross-origin requests
+type ConfigCORS struct {
+	// AllowedOrigins are the origins allowed to call the api ("*" allows any origin; empty disables CORS)
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in the cross-origin requests
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in the cross-origin requests
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers readable by the browser
+	ExposedHeaders []string
+	// AllowCredentials allows the cookies and the authorization header (not with the origin "*")
+	AllowCredentials bool
+	// MaxAge is the time the browser caches a preflight response
+	MaxAge time.Duration
+}
+
+// CORS is a middleware that answers the preflight requests and sets the CORS headers of the allowed origins
+type CORS struct {
+	// origins are the allowed origins
+	origins []string
+	// any allows any origin
+	any bool
+	// methods, headers and exposed are the values of the Access-Control-Allow-* / Expose-Headers headers
+	methods string
+	headers string
+	exposed string
+	// credentials allows the cookies and the authorization header
+	credentials bool
+	// maxAge is the value of the Access-Control-Max-Age header (empty to omit it)
+	maxAge string
+}
+
+// NewCORS creates a new CORS.
+func NewCORS(cfg ConfigCORS) *CORS {
+	// default config / values
+	if len(cfg.AllowedMethods) == 0 {
+		cfg.AllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if len(cfg.AllowedHeaders) == 0 {
+		cfg.AllowedHeaders = []string{"Authorization", "Content-Type", "If-Match", "If-None-Match", HeaderRequestID}
+	}
+
+	c := &CORS{
+		origins:     cfg.AllowedOrigins,
+		any:         slices.Contains(cfg.AllowedOrigins, "*"),
+		methods:     strings.Join(cfg.AllowedMethods, ", "),
+		headers:     strings.Join(cfg.AllowedHeaders, ", "),
+		exposed:     strings.Join(cfg.ExposedHeaders, ", "),
+		credentials: cfg.AllowCredentials,
+	}
+	if cfg.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+	return c
+}
+
+// Handle sets the CORS headers of the requests of an allowed origin, answering its preflight requests.
+// - requests of other origins go on without CORS headers, so the browser blocks their responses
+func (c *CORS) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// logic before
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		if origin == "" || !c.allowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the origin is echoed instead of "*", as "*" is not valid with credentials
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if c.credentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		// preflight
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", c.methods)
+			w.Header().Set("Access-Control-Allow-Headers", c.headers)
+			if c.maxAge != "" {
+				w.Header().Set("Access-Control-Max-Age", c.maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if c.exposed != "" {
+			w.Header().Set("Access-Control-Expose-Headers", c.exposed)
+		}
+
+		// call next handler
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowed returns if an origin is allowed
+func (c *CORS) allowed(origin string) bool {
+	return c.any || slices.Contains(c.origins, origin)
+}
@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"app/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS_Handle(t *testing.T) {
+	// arrange
+	cors := middleware.NewCORS(middleware.ConfigCORS{
+		AllowedOrigins: []string{"https://backoffice.example.com"},
+		ExposedHeaders: []string{middleware.HeaderRequestID},
+		MaxAge:         10 * time.Minute,
+	})
+	handler := cors.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	t.Run("success 01 - should answer the preflight of an allowed origin", func(t *testing.T) {
+		// act
+		req := httptest.NewRequest("OPTIONS", "/products", nil)
+		req.Header.Set("Origin", "https://backoffice.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		require.Equal(t, http.StatusNoContent, res.Code)
+		require.Equal(t, "https://backoffice.example.com", res.Header().Get("Access-Control-Allow-Origin"))
+		require.Contains(t, res.Header().Get("Access-Control-Allow-Methods"), "POST")
+		require.Equal(t, "600", res.Header().Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("success 02 - should expose the headers to an allowed origin", func(t *testing.T) {
+		// act
+		req := httptest.NewRequest("GET", "/products", nil)
+		req.Header.Set("Origin", "https://backoffice.example.com")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Equal(t, middleware.HeaderRequestID, res.Header().Get("Access-Control-Expose-Headers"))
+	})
+
+	t.Run("failure 01 - other origin gets no cors headers", func(t *testing.T) {
+		// act
+		req := httptest.NewRequest("GET", "/products", nil)
+		req.Header.Set("Origin", "https://evil.example.com")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Empty(t, res.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
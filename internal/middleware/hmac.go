@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"app/internal"
+	"app/platform/web/response"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// headers of a signed request
+const (
+	HeaderSignatureKeyID     = "X-Signature-Key-Id"
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+	HeaderSignatureNonce     = "X-Signature-Nonce"
+	HeaderSignature          = "X-Signature"
+)
+
+// errors
+var (
+	ErrHMACKeysFile     = errors.New("invalid hmac keys file")
+	ErrSignatureInvalid = errors.New("invalid signature")
+)
+
+// HMACKey is a struct that represents an entry of the hmac keys file
+type HMACKey struct {
+	// ID identifies the key (sent in X-Signature-Key-Id)
+	ID string `json:"id"`
+	// Secret is the shared secret used to sign
+	Secret string `json:"secret"`
+	// Scopes are the permissions granted to the key
+	Scopes []string `json:"scopes"`
+}
+
+// ConfigHMAC is a struct that represents the configuration of the hmac authentication
+type ConfigHMAC struct {
+	// Keys are the keys of the clients
+	Keys []HMACKey
+	// Window is the maximum difference between the timestamp of a request and the clock (by default 5 minutes)
+	Window time.Duration
+	// MaxBodyBytes is the maximum size of a signed body (by default 1MB)
+	MaxBodyBytes int64
+}
+
+// HMAC is a middleware that authenticates machine to machine requests signed with HMAC-SHA256.
+// - the signature is the hex HMAC-SHA256 of "METHOD\nPATH\nTIMESTAMP\nNONCE\nhex(SHA256(BODY))"
+// - a request is rejected if its timestamp is outside the window or its nonce was already used
+type HMAC struct {
+	keys         map[string]HMACKey
+	window       time.Duration
+	maxBodyBytes int64
+	// nonces are the nonces seen in the window, with the moment they can be forgotten
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	// now returns the current time
+	now func() time.Time
+}
+
+// NewHMAC creates a new HMAC.
+func NewHMAC(cfg ConfigHMAC) (h *HMAC, err error) {
+	// default config / values
+	if cfg.Window <= 0 {
+		cfg.Window = 5 * time.Minute
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 1 << 20
+	}
+
+	h = &HMAC{
+		keys:         make(map[string]HMACKey, len(cfg.Keys)),
+		window:       cfg.Window,
+		maxBodyBytes: cfg.MaxBodyBytes,
+		nonces:       make(map[string]time.Time),
+		now:          time.Now,
+	}
+	for i, k := range cfg.Keys {
+		if k.ID == "" || k.Secret == "" {
+			return nil, fmt.Errorf("%w: key %d: id and secret are required", ErrHMACKeysFile, i)
+		}
+		h.keys[k.ID] = k
+	}
+	return
+}
+
+// LoadHMACKeys reads the keys of a json file.
+func LoadHMACKeys(path string) (keys []HMACKey, err error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrHMACKeysFile, err)
+	}
+	if err = json.Unmarshal(bytes, &keys); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrHMACKeysFile, path, err)
+	}
+	return
+}
+
+// Authenticate returns a middleware that lets through the signed requests whose key has all the scopes.
+// - requests without X-Signature are handled by fallback (e.g. the api keys)
+func (h *HMAC) Authenticate(fallback func(http.Handler) http.Handler, scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		other := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// logic before
+			if r.Header.Get(HeaderSignature) == "" {
+				other.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := h.verify(r)
+			if err != nil {
+				response.Error(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+
+			principal := internal.Principal{Subject: key.ID, Scopes: key.Scopes}
+			for _, scope := range scopes {
+				if !principal.HasScope(scope) {
+					response.Errorf(w, http.StatusForbidden, "hmac key %s lacks the scope %s", key.ID, scope)
+					return
+				}
+			}
+
+			// call next handler
+			next.ServeHTTP(w, r.WithContext(internal.ContextWithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// verify checks the signature of a request and consumes its nonce
+// - the body is read and restored, so the handler can read it again
+func (h *HMAC) verify(r *http.Request) (key HMACKey, err error) {
+	// key
+	key, ok := h.keys[r.Header.Get(HeaderSignatureKeyID)]
+	if !ok {
+		return key, fmt.Errorf("%w: unknown key", ErrSignatureInvalid)
+	}
+
+	// timestamp
+	timestamp := r.Header.Get(HeaderSignatureTimestamp)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return key, fmt.Errorf("%w: malformed timestamp", ErrSignatureInvalid)
+	}
+	now := h.now()
+	if diff := now.Sub(time.Unix(seconds, 0)); diff > h.window || diff < -h.window {
+		return key, fmt.Errorf("%w: timestamp outside the window", ErrSignatureInvalid)
+	}
+
+	// nonce
+	nonce := r.Header.Get(HeaderSignatureNonce)
+	if nonce == "" || len(nonce) > 128 {
+		return key, fmt.Errorf("%w: malformed nonce", ErrSignatureInvalid)
+	}
+
+	// body
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(r.Body, h.maxBodyBytes+1))
+		if err != nil {
+			return key, fmt.Errorf("%w: cannot read the body", ErrSignatureInvalid)
+		}
+		if int64(len(body)) > h.maxBodyBytes {
+			return key, fmt.Errorf("%w: body too large", ErrSignatureInvalid)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	// signature
+	signature, err := hex.DecodeString(r.Header.Get(HeaderSignature))
+	if err != nil {
+		return key, fmt.Errorf("%w: malformed signature", ErrSignatureInvalid)
+	}
+	expected := Sign(key.Secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal(signature, expected) {
+		return key, fmt.Errorf("%w: bad signature", ErrSignatureInvalid)
+	}
+
+	// replay: only a valid signature consumes the nonce
+	if !h.useNonce(key.ID+":"+nonce, now) {
+		return key, fmt.Errorf("%w: nonce already used", ErrSignatureInvalid)
+	}
+	return
+}
+
+// useNonce records a nonce, returning false if it was already used in the window
+func (h *HMAC) useNonce(nonce string, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// forget the nonces outside the window
+	for n, expiresAt := range h.nonces {
+		if now.After(expiresAt) {
+			delete(h.nonces, n)
+		}
+	}
+
+	if _, ok := h.nonces[nonce]; ok {
+		return false
+	}
+	// a timestamp is accepted up to a window in the future, so the nonce is kept twice the window
+	h.nonces[nonce] = now.Add(2 * h.window)
+	return true
+}
+
+// Sign returns the HMAC-SHA256 signature of a request
+func Sign(secret, method, path, timestamp, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{strings.ToUpper(method), path, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return mac.Sum(nil)
+}
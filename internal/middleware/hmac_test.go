@@ -0,0 +1,117 @@
+package middleware_test
+
+import (
+	"app/internal"
+	"app/internal/middleware"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMAC_Authenticate(t *testing.T) {
+	// arrange
+	signed, err := middleware.NewHMAC(middleware.ConfigHMAC{
+		Keys: []middleware.HMACKey{
+			{ID: "erp", Secret: "s3cret", Scopes: []string{middleware.ScopeProductsWrite}},
+		},
+		Window: time.Minute,
+	})
+	require.NoError(t, err)
+	fallback := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+	}
+	var principal internal.Principal
+	var body string
+	handler := signed.Authenticate(fallback, middleware.ScopeProductsWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = internal.PrincipalFromContext(r.Context())
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	newRequest := func(secret, nonce string, timestamp time.Time, payload string) *http.Request {
+		ts := strconv.FormatInt(timestamp.Unix(), 10)
+		req := httptest.NewRequest("POST", "/products?x=1", strings.NewReader(payload))
+		req.Header.Set(middleware.HeaderSignatureKeyID, "erp")
+		req.Header.Set(middleware.HeaderSignatureTimestamp, ts)
+		req.Header.Set(middleware.HeaderSignatureNonce, nonce)
+		req.Header.Set(middleware.HeaderSignature, hex.EncodeToString(middleware.Sign(secret, "POST", "/products?x=1", ts, nonce, []byte(payload))))
+		return req
+	}
+
+	t.Run("success 01 - should let through a signed request, keeping the body", func(t *testing.T) {
+		// act
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, newRequest("s3cret", "nonce-1", time.Now(), `{"name":"a"}`))
+
+		// assert
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Equal(t, "erp", principal.Subject)
+		require.Equal(t, `{"name":"a"}`, body)
+	})
+
+	t.Run("success 02 - a request without signature is handled by the fallback", func(t *testing.T) {
+		// act
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest("POST", "/products", nil))
+
+		// assert
+		require.Equal(t, http.StatusTeapot, res.Code)
+	})
+
+	t.Run("failure 01 - replayed nonce", func(t *testing.T) {
+		// act
+		res01 := httptest.NewRecorder()
+		handler.ServeHTTP(res01, newRequest("s3cret", "nonce-2", time.Now(), `{}`))
+		res02 := httptest.NewRecorder()
+		handler.ServeHTTP(res02, newRequest("s3cret", "nonce-2", time.Now(), `{}`))
+
+		// assert
+		require.Equal(t, http.StatusOK, res01.Code)
+		require.Equal(t, http.StatusUnauthorized, res02.Code)
+		require.Contains(t, res02.Body.String(), "nonce already used")
+	})
+
+	t.Run("failure 02 - timestamp outside the window", func(t *testing.T) {
+		// act
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, newRequest("s3cret", "nonce-3", time.Now().Add(-2*time.Minute), `{}`))
+
+		// assert
+		require.Equal(t, http.StatusUnauthorized, res.Code)
+		require.Contains(t, res.Body.String(), "timestamp outside the window")
+	})
+
+	t.Run("failure 03 - tampered body", func(t *testing.T) {
+		// arrange
+		req := newRequest("s3cret", "nonce-4", time.Now(), `{"price":1}`)
+		req.Body = io.NopCloser(strings.NewReader(`{"price":0}`))
+
+		// act
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		require.Equal(t, http.StatusUnauthorized, res.Code)
+		require.Contains(t, res.Body.String(), "bad signature")
+	})
+
+	t.Run("failure 04 - wrong secret does not consume the nonce", func(t *testing.T) {
+		// act
+		res01 := httptest.NewRecorder()
+		handler.ServeHTTP(res01, newRequest("other", "nonce-5", time.Now(), `{}`))
+		res02 := httptest.NewRecorder()
+		handler.ServeHTTP(res02, newRequest("s3cret", "nonce-5", time.Now(), `{}`))
+
+		// assert
+		require.Equal(t, http.StatusUnauthorized, res01.Code)
+		require.Equal(t, http.StatusOK, res02.Code)
+	})
+}
@@ -0,0 +1,311 @@
+package middleware
+
+import (
+	"app/internal"
+	"app/platform/web/response"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// errors
+var (
+	ErrJWKSFile     = errors.New("invalid jwks file")
+	ErrTokenInvalid = errors.New("invalid token")
+)
+
+// ConfigJWT is a struct that represents the configuration of the JWT authentication
+type ConfigJWT struct {
+	// Secret is the shared secret of the HS256 tokens (empty disables HS256)
+	Secret string
+	// JWKSFile is the path of the json web key set with the RS256 / ES256 public keys (empty disables them)
+	JWKSFile string
+	// Issuer is the expected "iss" claim (empty accepts any)
+	Issuer string
+	// Audience is the expected "aud" claim (empty accepts any)
+	Audience string
+	// Leeway is the clock skew tolerated validating "exp" and "nbf"
+	Leeway time.Duration
+}
+
+// JWT is a middleware that authenticates the request with a signed JSON web token verified locally.
+// - HS256 tokens are verified with a shared secret, RS256 / ES256 tokens with the keys of a JWKS file
+// - the claims "sub" and "scope" are stored in the context of the request as its principal
+type JWT struct {
+	secret   []byte
+	keys     map[string]crypto.PublicKey
+	issuer   string
+	audience string
+	leeway   time.Duration
+	// now returns the current time
+	now func() time.Time
+}
+
+// NewJWT creates a new JWT.
+func NewJWT(cfg ConfigJWT) (j *JWT, err error) {
+	j = &JWT{
+		secret:   []byte(cfg.Secret),
+		keys:     make(map[string]crypto.PublicKey),
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		leeway:   cfg.Leeway,
+		now:      time.Now,
+	}
+
+	if cfg.JWKSFile != "" {
+		if j.keys, err = loadJWKS(cfg.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// Authenticate returns a middleware that lets through the requests with a valid token that has all the scopes.
+// - requests whose bearer token is not a JWT are handled by fallback (e.g. the api keys)
+func (j *JWT) Authenticate(fallback func(http.Handler) http.Handler, scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		other := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// logic before
+			token := bearerToken(r)
+			if strings.Count(token, ".") != 2 {
+				other.ServeHTTP(w, r)
+				return
+			}
+
+			principal, err := j.Verify(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="products", error="invalid_token"`)
+				response.Error(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			for _, scope := range scopes {
+				if !principal.HasScope(scope) {
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="products", error="insufficient_scope", scope=%q`, strings.Join(scopes, " ")))
+					response.Errorf(w, http.StatusForbidden, "token of %s lacks the scope %s", principal.Subject, scope)
+					return
+				}
+			}
+
+			// call next handler
+			next.ServeHTTP(w, r.WithContext(internal.ContextWithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// jwtHeader is the header of a token
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwtClaims are the claims of a token used to authenticate
+type jwtClaims struct {
+	Sub   string          `json:"sub"`
+	Scope string          `json:"scope"`
+	Iss   string          `json:"iss"`
+	Aud   json.RawMessage `json:"aud"`
+	Exp   *float64        `json:"exp"`
+	Nbf   *float64        `json:"nbf"`
+}
+
+// Verify checks the signature and the claims of a token and returns its principal
+func (j *JWT) Verify(token string) (principal internal.Principal, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return principal, fmt.Errorf("%w: malformed", ErrTokenInvalid)
+	}
+
+	// header
+	var header jwtHeader
+	if err = decodeSegment(parts[0], &header); err != nil {
+		return
+	}
+
+	// signature
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return principal, fmt.Errorf("%w: malformed signature", ErrTokenInvalid)
+	}
+	if err = j.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return
+	}
+
+	// claims
+	var claims jwtClaims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return
+	}
+	if err = j.validateClaims(claims); err != nil {
+		return
+	}
+
+	principal = internal.Principal{Subject: claims.Sub, Scopes: strings.Fields(claims.Scope)}
+	return
+}
+
+// verifySignature checks the signature of the signing input with the algorithm of the header
+func (j *JWT) verifySignature(header jwtHeader, input string, signature []byte) (err error) {
+	digest := sha256.Sum256([]byte(input))
+
+	switch header.Alg {
+	case "HS256":
+		if len(j.secret) == 0 {
+			return fmt.Errorf("%w: HS256 is not enabled", ErrTokenInvalid)
+		}
+		mac := hmac.New(sha256.New, j.secret)
+		mac.Write([]byte(input))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return fmt.Errorf("%w: bad signature", ErrTokenInvalid)
+		}
+	case "RS256":
+		key, ok := j.keys[header.Kid].(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: unknown RS256 key %q", ErrTokenInvalid, header.Kid)
+		}
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+			return fmt.Errorf("%w: bad signature", ErrTokenInvalid)
+		}
+	case "ES256":
+		key, ok := j.keys[header.Kid].(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: unknown ES256 key %q", ErrTokenInvalid, header.Kid)
+		}
+		if len(signature) != 64 {
+			return fmt.Errorf("%w: bad signature", ErrTokenInvalid)
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return fmt.Errorf("%w: bad signature", ErrTokenInvalid)
+		}
+	default:
+		return fmt.Errorf("%w: algorithm %q is not supported", ErrTokenInvalid, header.Alg)
+	}
+	return
+}
+
+// validateClaims checks the time, issuer and audience claims
+func (j *JWT) validateClaims(claims jwtClaims) (err error) {
+	now := j.now()
+	if claims.Exp == nil {
+		return fmt.Errorf("%w: exp is required", ErrTokenInvalid)
+	}
+	if now.After(unixTime(*claims.Exp).Add(j.leeway)) {
+		return fmt.Errorf("%w: expired", ErrTokenInvalid)
+	}
+	if claims.Nbf != nil && now.Add(j.leeway).Before(unixTime(*claims.Nbf)) {
+		return fmt.Errorf("%w: not valid yet", ErrTokenInvalid)
+	}
+	if claims.Sub == "" {
+		return fmt.Errorf("%w: sub is required", ErrTokenInvalid)
+	}
+	if j.issuer != "" && claims.Iss != j.issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrTokenInvalid)
+	}
+	if j.audience != "" && !hasAudience(claims.Aud, j.audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrTokenInvalid)
+	}
+	return
+}
+
+// hasAudience checks if the "aud" claim (a string or a list) contains an audience
+func hasAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == audience
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		for _, a := range list {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeSegment decodes a base64url json segment of a token
+func decodeSegment(segment string, ptr any) (err error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed segment", ErrTokenInvalid)
+	}
+	if err = json.Unmarshal(bytes, ptr); err != nil {
+		return fmt.Errorf("%w: malformed segment", ErrTokenInvalid)
+	}
+	return
+}
+
+// unixTime converts a numeric date of a claim
+func unixTime(seconds float64) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
+
+// jwk is a key of a json web key set
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS reads the RSA and P-256 public keys of a json web key set, by key id
+func loadJWKS(path string) (keys map[string]crypto.PublicKey, err error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJWKSFile, err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.Unmarshal(bytes, &set); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrJWKSFile, path, err)
+	}
+
+	keys = make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		var key crypto.PublicKey
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("%w: key %q: invalid RSA key", ErrJWKSFile, k.Kid)
+			}
+			key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if k.Crv != "P-256" || errX != nil || errY != nil {
+				return nil, fmt.Errorf("%w: key %q: invalid P-256 key", ErrJWKSFile, k.Kid)
+			}
+			ec := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			if !ec.Curve.IsOnCurve(ec.X, ec.Y) {
+				return nil, fmt.Errorf("%w: key %q: point is not on the curve", ErrJWKSFile, k.Kid)
+			}
+			key = ec
+		default:
+			// keys of other types are ignored
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return
+}
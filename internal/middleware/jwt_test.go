@@ -0,0 +1,146 @@
+package middleware_test
+
+import (
+	"app/internal"
+	"app/internal/middleware"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// NewJWT signs a token with the claims
+// - key is the secret ([]byte), a *rsa.PrivateKey or an *ecdsa.PrivateKey
+func NewJWT(t *testing.T, alg string, kid string, key any, claims map[string]any) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWT_Authenticate(t *testing.T) {
+	// arrange
+	// - keys
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"rsa-1","n":%q,"e":%q},
+		{"kty":"EC","kid":"ec-1","crv":"P-256","x":%q,"y":%q}
+	]}`, b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()), b64(ecKey.X.Bytes()), b64(ecKey.Y.Bytes()))
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(jwksFile, []byte(jwks), 0600))
+	// - middleware
+	secret := []byte("shared-secret")
+	jwt, err := middleware.NewJWT(middleware.ConfigJWT{Secret: string(secret), JWKSFile: jwksFile, Issuer: "sso"})
+	require.NoError(t, err)
+	fallback := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+	}
+	var principal internal.Principal
+	handler := jwt.Authenticate(fallback, middleware.ScopeProductsWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = internal.PrincipalFromContext(r.Context())
+	}))
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/products", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+	claims := map[string]any{"sub": "jdoe", "scope": "products:read products:write", "iss": "sso", "exp": time.Now().Add(time.Hour).Unix()}
+
+	t.Run("success 01 - should accept HS256, RS256 and ES256 tokens", func(t *testing.T) {
+		tokens := []string{
+			NewJWT(t, "HS256", "", secret, claims),
+			NewJWT(t, "RS256", "rsa-1", rsaKey, claims),
+			NewJWT(t, "ES256", "ec-1", ecKey, claims),
+		}
+		for _, token := range tokens {
+			// act
+			principal = internal.Principal{}
+			res := serve(token)
+
+			// assert
+			require.Equal(t, http.StatusOK, res.Code)
+			require.Equal(t, "jdoe", principal.Subject)
+			require.Equal(t, []string{"products:read", "products:write"}, principal.Scopes)
+		}
+	})
+
+	t.Run("success 02 - should hand over other tokens to the fallback", func(t *testing.T) {
+		// act
+		res := serve("123456")
+
+		// assert
+		require.Equal(t, http.StatusTeapot, res.Code)
+	})
+
+	t.Run("failure 01 - invalid tokens", func(t *testing.T) {
+		expired := map[string]any{"sub": "jdoe", "scope": "products:write", "iss": "sso", "exp": time.Now().Add(-time.Hour).Unix()}
+		otherIssuer := map[string]any{"sub": "jdoe", "scope": "products:write", "iss": "other", "exp": time.Now().Add(time.Hour).Unix()}
+		wrongKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		tokens := []string{
+			NewJWT(t, "HS256", "", secret, expired),
+			NewJWT(t, "HS256", "", secret, otherIssuer),
+			NewJWT(t, "HS256", "", []byte("other-secret"), claims),
+			NewJWT(t, "RS256", "rsa-1", wrongKey, claims),
+			NewJWT(t, "RS256", "unknown", rsaKey, claims),
+			strings.Replace(NewJWT(t, "HS256", "", secret, claims), "eyJhbGciOiJIUzI1NiIs", "eyJhbGciOiJub25lIiwi", 1),
+		}
+		for _, token := range tokens {
+			// act
+			res := serve(token)
+
+			// assert
+			require.Equal(t, http.StatusUnauthorized, res.Code, token)
+		}
+	})
+
+	t.Run("failure 02 - token without the scope", func(t *testing.T) {
+		// act
+		res := serve(NewJWT(t, "HS256", "", secret, map[string]any{"sub": "jdoe", "scope": "products:read", "iss": "sso", "exp": time.Now().Add(time.Hour).Unix()}))
+
+		// assert
+		require.Equal(t, http.StatusForbidden, res.Code)
+	})
+}
@@ -1,26 +1,137 @@
 package middleware
 
 import (
+	"app/internal"
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
 	"time"
+
+	"github.com/go-chi/chi/v5"
 )
 
-type Logger struct{}
+// ConfigLogger is a struct that represents the configuration of the access log
+type ConfigLogger struct {
+	// Output is the sink of the entries (by default os.Stdout)
+	Output io.Writer
+	// Format is the format of the entries: "json" or "text" (key=value)
+	Format string
+	// Level is the minimum level of the entries written
+	// - entries are written as info, or warn / error for 4xx / 5xx responses
+	Level slog.Level
+	// SkipPaths are the paths whose requests are not logged (e.g. the probes of the orchestrator)
+	SkipPaths []string
+}
+
+// OpenLogOutput opens the sink of the logs: "stdout", "stderr" or the path of a file (the entries are appended to it)
+// - closing stdout or stderr does nothing
+func OpenLogOutput(output string) (w io.WriteCloser, err error) {
+	switch output {
+	case "", "stdout":
+		return nopCloser{os.Stdout}, nil
+	case "stderr":
+		return nopCloser{os.Stderr}, nil
+	}
+
+	file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open the log output %s: %w", output, err)
+	}
+	return file, nil
+}
+
+// nopCloser is a writer whose Close does nothing
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// Logger is a middleware that writes an access log entry per request
+type Logger struct {
+	logger *slog.Logger
+	// skipPaths are the paths whose requests are not logged
+	skipPaths map[string]bool
+}
+
+// NewLogger creates a new Logger.
+func NewLogger(cfg ConfigLogger) *Logger {
+	skipPaths := make(map[string]bool, len(cfg.SkipPaths))
+	for _, path := range cfg.SkipPaths {
+		skipPaths[path] = true
+	}
+
+	return &Logger{
+		logger:    NewSlogLogger(cfg),
+		skipPaths: skipPaths,
+	}
+}
+
+// NewSlogLogger creates the slog logger of a configuration (also used by the rest of the application)
+func NewSlogLogger(cfg ConfigLogger) *slog.Logger {
+	// default config / values
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
+	}
 
-func NewLogger() *Logger {
-	return &Logger{}
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	switch cfg.Format {
+	case "text":
+		return slog.New(slog.NewTextHandler(cfg.Output, opts))
+	default:
+		return slog.New(slog.NewJSONHandler(cfg.Output, opts))
+	}
 }
 
+// Log writes the entry of the request once the handler has responded.
 func (l *Logger) Log(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// logic before
-		fmt.Printf("Method: %s\n", r.Method)
-		fmt.Printf("Date: %s\n", time.Now().Format("2006-01-02 15:04:05"))
-		fmt.Printf("URL: %s\n", r.URL)
-		fmt.Printf("Bytes: %d\n", r.ContentLength)
+		if l.skipPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		rw := newResponseWriter(w)
+		body := &countingBody{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
 
 		// call next handler
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(rw, r)
+
+		// logic after
+		level := slog.LevelInfo
+		switch {
+		case rw.status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case rw.status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		l.logger.LogAttrs(context.Background(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("route", routePattern(r)),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rw.status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int64("bytes_in", body.bytes),
+			slog.Int("bytes_out", rw.bytes),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("request_id", internal.RequestIDFromContext(r.Context())),
+		)
 	})
 }
+
+// routePattern returns the pattern of the chi route that handled the request (e.g. /products/{id})
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
@@ -0,0 +1,118 @@
+package middleware_test
+
+import (
+	"app/internal/middleware"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_Log(t *testing.T) {
+	t.Run("success 01 - should write an entry with the response", func(t *testing.T) {
+		// arrange
+		var out bytes.Buffer
+		logger := middleware.NewLogger(middleware.ConfigLogger{Output: &out, Format: "json", Level: slog.LevelInfo})
+		rt := chi.NewRouter()
+		rt.Use(middleware.NewRequestID().Handle)
+		rt.Use(logger.Log)
+		rt.Post("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+			io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("created"))
+		})
+
+		// act
+		req := httptest.NewRequest("POST", "/products/1", strings.NewReader(`{"name":"product"}`))
+		req.Header.Set("X-Request-ID", "abc")
+		res := httptest.NewRecorder()
+		rt.ServeHTTP(res, req)
+
+		// assert
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+		require.Equal(t, "INFO", entry["level"])
+		require.Equal(t, "POST", entry["method"])
+		require.Equal(t, "/products/{id}", entry["route"])
+		require.Equal(t, "/products/1", entry["path"])
+		require.Equal(t, float64(http.StatusCreated), entry["status"])
+		require.Equal(t, float64(18), entry["bytes_in"])
+		require.Equal(t, float64(7), entry["bytes_out"])
+		require.Equal(t, "abc", entry["request_id"])
+		require.Contains(t, entry, "latency")
+	})
+
+	t.Run("success 02 - should skip the entries below the level", func(t *testing.T) {
+		// arrange
+		var out bytes.Buffer
+		logger := middleware.NewLogger(middleware.ConfigLogger{Output: &out, Format: "text", Level: slog.LevelWarn})
+		handler := logger.Log(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		// act
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/products", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+		// assert
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 1)
+		require.Contains(t, lines[0], "level=WARN")
+		require.Contains(t, lines[0], "status=404")
+	})
+
+	t.Run("success 03 - should skip the configured paths", func(t *testing.T) {
+		// arrange
+		var out bytes.Buffer
+		logger := middleware.NewLogger(middleware.ConfigLogger{Output: &out, Format: "text", Level: slog.LevelInfo, SkipPaths: []string{"/healthz"}})
+		handler := logger.Log(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		// act
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/products", nil))
+
+		// assert
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 1)
+		require.Contains(t, lines[0], "path=/products")
+	})
+}
+
+func TestOpenLogOutput(t *testing.T) {
+	t.Run("success 01 - should append the entries to a file", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "access.log")
+		require.NoError(t, os.WriteFile(path, []byte("previous\n"), 0644))
+
+		// act
+		output, err := middleware.OpenLogOutput(path)
+		require.NoError(t, err)
+		middleware.NewSlogLogger(middleware.ConfigLogger{Output: output, Format: "text"}).Info("started")
+		require.NoError(t, output.Close())
+
+		// assert
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(content), "previous\n"))
+		require.Contains(t, string(content), "msg=started")
+	})
+
+	t.Run("failure 01 - directory of the file does not exist", func(t *testing.T) {
+		// act
+		_, err := middleware.OpenLogOutput(filepath.Join(t.TempDir(), "missing", "access.log"))
+
+		// assert
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"app/internal/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics is a middleware that counts the requests and observes their latency by route and status
+type Metrics struct {
+	// requests is the number of requests
+	requests *metrics.CounterVec
+	// duration is the latency of the requests
+	duration *metrics.HistogramVec
+}
+
+// NewMetrics creates a new Metrics, registering its metrics in reg.
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	return &Metrics{
+		requests: reg.NewCounterVec("http_requests_total", "Number of http requests.", "method", "route", "status"),
+		duration: reg.NewHistogramVec("http_request_duration_seconds", "Latency of the http requests in seconds.", metrics.DefaultBuckets, "method", "route", "status"),
+	}
+}
+
+// Observe records the request once the handler has responded.
+// - the route is the chi pattern (e.g. /products/{id}), so the paths do not create a series each
+func (m *Metrics) Observe(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// logic before
+		start := time.Now()
+		rw := newResponseWriter(w)
+
+		// call next handler
+		next.ServeHTTP(rw, r)
+
+		// logic after
+		route := routePattern(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		method := methodLabel(r.Method)
+		status := strconv.Itoa(rw.status)
+		m.requests.Inc(method, route, status)
+		m.duration.Observe(time.Since(start).Seconds(), method, route, status)
+	})
+}
+
+// methodLabel returns the method of a request as a label
+// - any token is a valid method, so the non standard ones are grouped as OTHER to not create a series each
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
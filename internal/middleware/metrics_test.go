@@ -0,0 +1,40 @@
+package middleware_test
+
+import (
+	"app/internal/metrics"
+	"app/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_Observe(t *testing.T) {
+	t.Run("success 01 - should label the requests by route pattern and status", func(t *testing.T) {
+		// arrange
+		reg := metrics.NewRegistry()
+		rt := chi.NewRouter()
+		rt.Use(middleware.NewMetrics(reg).Observe)
+		rt.Get("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+
+		// act
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/products/1", nil))
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/products/2", nil))
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("FOO1", "/missing", nil))
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("FOO2", "/missing", nil))
+		var out strings.Builder
+		reg.WriteTo(&out)
+
+		// assert
+		require.Contains(t, out.String(), `http_requests_total{method="GET",route="/products/{id}",status="404"} 2`)
+		require.Contains(t, out.String(), `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+		require.Contains(t, out.String(), `http_requests_total{method="OTHER",route="unmatched",status="405"} 2`)
+		require.Contains(t, out.String(), `http_request_duration_seconds_count{method="GET",route="/products/{id}",status="404"} 2`)
+	})
+}
@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"app/internal"
+	"app/platform/web/response"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// headers of the rate limit
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+// errors
+var (
+	ErrRateLimitInvalid = errors.New("invalid rate limit")
+)
+
+// RateLimit is a struct that represents the limit of a token bucket
+// - the bucket holds up to Burst tokens and refills Requests tokens every Per
+type RateLimit struct {
+	// Requests is the number of requests allowed every Per (zero disables the limit)
+	Requests int
+	// Per is the period of the limit
+	Per time.Duration
+	// Burst is the capacity of the bucket (by default Requests)
+	Burst int
+}
+
+// Enabled returns if the limit restricts the requests
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// capacity returns the size of the bucket
+func (l RateLimit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate returns the tokens refilled per second
+func (l RateLimit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// ParseRateLimit parses a limit written as "requests/period[:burst]", e.g. "60/1m" or "10/1s:20"
+func ParseRateLimit(s string) (l RateLimit, err error) {
+	s, burst, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	requests, per, found := strings.Cut(s, "/")
+	if !found {
+		return l, fmt.Errorf("%w: %q is not requests/period", ErrRateLimitInvalid, s)
+	}
+	if l.Requests, err = strconv.Atoi(requests); err != nil || l.Requests < 0 {
+		return l, fmt.Errorf("%w: invalid requests %q", ErrRateLimitInvalid, requests)
+	}
+	if l.Per, err = time.ParseDuration(per); err != nil || l.Per <= 0 {
+		return l, fmt.Errorf("%w: invalid period %q", ErrRateLimitInvalid, per)
+	}
+	if hasBurst {
+		if l.Burst, err = strconv.Atoi(burst); err != nil || l.Burst < 0 {
+			return l, fmt.Errorf("%w: invalid burst %q", ErrRateLimitInvalid, burst)
+		}
+	}
+	return l, nil
+}
+
+// RateLimitResult is a struct that represents the outcome of taking a token
+type RateLimitResult struct {
+	// Allowed is true if a token was taken
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until a token is available (zero if Allowed)
+	RetryAfter time.Duration
+}
+
+// RateLimitStore is an interface that represents the storage of the token buckets
+// - it is in memory by default, and can be replaced by a shared store (e.g. redis) to limit across instances
+type RateLimitStore interface {
+	// Take takes a token of the bucket of key
+	Take(key string, limit RateLimit, now time.Time) (result RateLimitResult, err error)
+}
+
+// bucket is the state of a token bucket
+type bucket struct {
+	// tokens is the number of tokens at updatedAt
+	tokens float64
+	// updatedAt is the moment of the last refill
+	updatedAt time.Time
+	// fullAt is the moment the bucket is full again, with the rate of its own limit
+	fullAt time.Time
+}
+
+// NewRateLimitStoreMemory creates a new RateLimitStoreMemory.
+func NewRateLimitStoreMemory() *RateLimitStoreMemory {
+	return &RateLimitStoreMemory{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// RateLimitStoreMemory is an in process implementation of RateLimitStore
+type RateLimitStoreMemory struct {
+	// mu protects the buckets
+	mu sync.Mutex
+	// buckets are the buckets by key
+	buckets map[string]*bucket
+	// takes is the number of takes since the last sweep of the full buckets
+	takes int
+}
+
+// sweepEvery is the number of takes after which the full buckets are forgotten
+const sweepEvery = 10000
+
+// Take takes a token of the bucket of key.
+func (s *RateLimitStoreMemory) Take(key string, limit RateLimit, now time.Time) (result RateLimitResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	capacity, rate := limit.capacity(), limit.rate()
+
+	// forget the buckets that are full again, they behave as a new one
+	// - each bucket is checked against its own limit, the one of this take may be another
+	s.takes++
+	if s.takes >= sweepEvery {
+		s.takes = 0
+		for k, b := range s.buckets {
+			if !now.Before(b.fullAt) {
+				delete(s.buckets, k)
+			}
+		}
+	}
+
+	// refill
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updatedAt).Seconds()*rate)
+	b.updatedAt = now
+
+	// take
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	b.fullAt = now.Add(result.Reset)
+	return
+}
+
+// seconds converts a number of seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// ConfigRateLimiter is a struct that represents the configuration of the rate limiter
+type ConfigRateLimiter struct {
+	// Store is the storage of the buckets (by default in memory)
+	Store RateLimitStore
+	// TrustProxy takes the client ip of the X-Forwarded-For header (only behind a trusted proxy)
+	TrustProxy bool
+}
+
+// RateLimiter is a middleware that limits the requests of each client with a token bucket per route.
+// - the client is identified by its authenticated principal or, without it, by its ip
+type RateLimiter struct {
+	// store is the storage of the buckets
+	store RateLimitStore
+	// trustProxy takes the client ip of the X-Forwarded-For header
+	trustProxy bool
+	// now returns the current time
+	now func() time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter.
+func NewRateLimiter(cfg ConfigRateLimiter) *RateLimiter {
+	// default config / values
+	if cfg.Store == nil {
+		cfg.Store = NewRateLimitStoreMemory()
+	}
+
+	return &RateLimiter{
+		store:      cfg.Store,
+		trustProxy: cfg.TrustProxy,
+		now:        time.Now,
+	}
+}
+
+// Limit returns a middleware that limits the requests to route of each client.
+// - a disabled limit lets every request through
+func (l *RateLimiter) Limit(route string, limit RateLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !limit.Enabled() {
+			return next
+		}
+		policy := fmt.Sprintf("%d;w=%d", int(limit.capacity()), int(math.Ceil(limit.Per.Seconds())))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// logic before
+			result, err := l.store.Take(route+"|"+l.client(r), limit, l.now())
+			if err != nil {
+				// a failure of the store does not take the api down
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(HeaderRateLimitPolicy, policy)
+			w.Header().Set(HeaderRateLimitLimit, strconv.Itoa(int(limit.capacity())))
+			w.Header().Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+			w.Header().Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(result.Reset)))
+			if !result.Allowed {
+				w.Header().Set(HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				response.Error(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+
+			// call next handler
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// client returns the identity of the client of a request
+//   - the authenticated principal (api key, jwt subject or hmac key) when the limiter runs after the authentication,
+//     otherwise the ip, as an unverified token would let a client pick a new bucket per request
+func (l *RateLimiter) client(r *http.Request) string {
+	if principal, ok := internal.PrincipalFromContext(r.Context()); ok {
+		return "principal:" + principal.Subject
+	}
+	return "ip:" + clientIP(r, l.trustProxy)
+}
+
+// clientIP returns the ip of the client of a request
+// - behind a proxy it is the last entry of X-Forwarded-For, the one appended by the proxy
+// (the previous ones are sent by the client, so it could pick a new one per request)
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		forwarded := r.Header.Values("X-Forwarded-For")
+		if len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(entries[len(entries)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,169 @@
+package middleware_test
+
+import (
+	"app/internal"
+	"app/internal/middleware"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Limit(t *testing.T) {
+	// arrange
+	limiter := middleware.NewRateLimiter(middleware.ConfigRateLimiter{})
+	handler := limiter.Limit("GET /products", middleware.RateLimit{Requests: 2, Per: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func(remoteAddr string, principal *internal.Principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/products", nil)
+		req.RemoteAddr = remoteAddr
+		if principal != nil {
+			req = req.WithContext(internal.ContextWithPrincipal(req.Context(), *principal))
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("success 01 - should let through the requests within the limit", func(t *testing.T) {
+		// act
+		res := request("10.0.0.1:1234", nil)
+
+		// assert
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Equal(t, "2", res.Header().Get(middleware.HeaderRateLimitLimit))
+		require.Equal(t, "1", res.Header().Get(middleware.HeaderRateLimitRemaining))
+		require.Equal(t, "2;w=60", res.Header().Get(middleware.HeaderRateLimitPolicy))
+	})
+
+	t.Run("success 02 - each client has its own bucket", func(t *testing.T) {
+		// act
+		resIP := request("10.0.0.2:1234", nil)
+		resPrincipal := request("10.0.0.1:1234", &internal.Principal{Subject: "warehouse"})
+
+		// assert
+		require.Equal(t, http.StatusOK, resIP.Code)
+		require.Equal(t, http.StatusOK, resPrincipal.Code)
+	})
+
+	t.Run("failure 01 - should reject the requests over the limit", func(t *testing.T) {
+		// act
+		request("10.0.0.3:1234", nil)
+		request("10.0.0.3:1234", nil)
+		res := request("10.0.0.3:1234", nil)
+
+		// assert
+		expectedBody := `{"status":"Too Many Requests","message":"rate limit exceeded"}`
+		require.Equal(t, http.StatusTooManyRequests, res.Code)
+		require.JSONEq(t, expectedBody, res.Body.String())
+		require.Equal(t, "30", res.Header().Get(middleware.HeaderRetryAfter))
+		require.Equal(t, "0", res.Header().Get(middleware.HeaderRateLimitRemaining))
+	})
+
+	t.Run("failure 02 - behind a proxy, the entries of X-Forwarded-For sent by the client are ignored", func(t *testing.T) {
+		// arrange
+		limiter := middleware.NewRateLimiter(middleware.ConfigRateLimiter{TrustProxy: true})
+		handler := limiter.Limit("GET /products", middleware.RateLimit{Requests: 2, Per: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		// act
+		var res *httptest.ResponseRecorder
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest("GET", "/products", nil)
+			req.RemoteAddr = "10.0.0.100:1234"
+			req.Header.Set("X-Forwarded-For", fmt.Sprintf("192.0.2.%d, 203.0.113.7", i))
+			res = httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+		}
+
+		// assert
+		require.Equal(t, http.StatusTooManyRequests, res.Code)
+	})
+
+	t.Run("failure 03 - before the authentication, the failed attempts are limited by ip", func(t *testing.T) {
+		// arrange
+		limiter := middleware.NewRateLimiter(middleware.ConfigRateLimiter{})
+		unauthorized := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnauthorized) })
+		handler := limiter.Limit("auth", middleware.RateLimit{Requests: 2, Per: time.Minute})(unauthorized)
+
+		// act
+		var codes []int
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest("POST", "/products", nil)
+			req.RemoteAddr = "10.0.0.4:1234"
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer guess-%d", i))
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+			codes = append(codes, res.Code)
+		}
+
+		// assert
+		require.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}, codes)
+	})
+}
+
+func TestRateLimitStoreMemory_Take(t *testing.T) {
+	t.Run("success 01 - the bucket refills over time up to the burst", func(t *testing.T) {
+		// arrange
+		store := middleware.NewRateLimitStoreMemory()
+		limit := middleware.RateLimit{Requests: 1, Per: time.Second, Burst: 3}
+		now := time.Now()
+
+		// act
+		for i := 0; i < 3; i++ {
+			result, err := store.Take("k", limit, now)
+			require.NoError(t, err)
+			require.True(t, result.Allowed)
+		}
+		empty, _ := store.Take("k", limit, now)
+		refilled, _ := store.Take("k", limit, now.Add(time.Second))
+		full, _ := store.Take("k", limit, now.Add(time.Hour))
+
+		// assert
+		require.False(t, empty.Allowed)
+		require.Equal(t, time.Second, empty.RetryAfter)
+		require.True(t, refilled.Allowed)
+		require.True(t, full.Allowed)
+		require.Equal(t, 2, full.Remaining)
+	})
+
+	t.Run("success 02 - the takes of a faster limit do not forget the buckets of a slower one", func(t *testing.T) {
+		// arrange
+		store := middleware.NewRateLimitStoreMemory()
+		slow := middleware.RateLimit{Requests: 1, Per: time.Hour}
+		fast := middleware.RateLimit{Requests: 1000, Per: time.Second}
+		now := time.Now()
+		first, _ := store.Take("slow", slow, now)
+
+		// act
+		// - enough takes to sweep the full buckets
+		for i := 0; i < 20000; i++ {
+			store.Take(fmt.Sprintf("fast-%d", i), fast, now.Add(time.Minute))
+		}
+		second, _ := store.Take("slow", slow, now.Add(time.Minute))
+
+		// assert
+		require.True(t, first.Allowed)
+		require.False(t, second.Allowed)
+	})
+}
+
+func TestParseRateLimit(t *testing.T) {
+	t.Run("success 01 - limit with burst", func(t *testing.T) {
+		// act
+		limit, err := middleware.ParseRateLimit("10/1s:20")
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, middleware.RateLimit{Requests: 10, Per: time.Second, Burst: 20}, limit)
+	})
+
+	t.Run("failure 01 - invalid limit", func(t *testing.T) {
+		// act
+		_, err := middleware.ParseRateLimit("600")
+
+		// assert
+		require.ErrorIs(t, err, middleware.ErrRateLimitInvalid)
+	})
+}
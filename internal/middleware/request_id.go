@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"app/internal"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// HeaderRequestID is the header that carries the id of a request
+const HeaderRequestID = "X-Request-ID"
+
+// RequestID is a middleware that accepts or generates the id of every request
+// - the id is stored in the context of the request and echoed in the response headers
+type RequestID struct{}
+
+// NewRequestID creates a new RequestID.
+func NewRequestID() *RequestID {
+	return &RequestID{}
+}
+
+// Handle sets the id of the request.
+func (m *RequestID) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// logic before
+		id := r.Header.Get(HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, id)
+
+		// call next handler
+		next.ServeHTTP(w, r.WithContext(internal.ContextWithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID checks that an id sent by a client is safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random id
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
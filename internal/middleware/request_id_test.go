@@ -0,0 +1,67 @@
+package middleware_test
+
+import (
+	"app/internal"
+	"app/internal/middleware"
+	"app/platform/web/response"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID_Handle(t *testing.T) {
+	t.Run("success 01 - should accept the id of the client", func(t *testing.T) {
+		// arrange
+		var id string
+		handler := middleware.NewRequestID().Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id = internal.RequestIDFromContext(r.Context())
+		}))
+
+		// act
+		req := httptest.NewRequest("GET", "/products", nil)
+		req.Header.Set("X-Request-ID", "client-id-1")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		require.Equal(t, "client-id-1", id)
+		require.Equal(t, "client-id-1", res.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("success 02 - should generate an id when it is missing or invalid", func(t *testing.T) {
+		// arrange
+		var id string
+		handler := middleware.NewRequestID().Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id = internal.RequestIDFromContext(r.Context())
+		}))
+
+		// act
+		req := httptest.NewRequest("GET", "/products", nil)
+		req.Header.Set("X-Request-ID", "bad id\n")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		require.Len(t, id, 32)
+		require.Equal(t, id, res.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("success 03 - should echo the id in the error bodies", func(t *testing.T) {
+		// arrange
+		handler := middleware.NewRequestID().Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response.Error(w, http.StatusNotFound, "product not found")
+		}))
+
+		// act
+		req := httptest.NewRequest("GET", "/products/1", nil)
+		req.Header.Set("X-Request-ID", "client-id-1")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		// assert
+		expectedBody := `{"status":"Not Found","message":"product not found","request_id":"client-id-1"}`
+		require.JSONEq(t, expectedBody, res.Body.String())
+	})
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+)
+
+// responseWriter is a http.ResponseWriter that captures the status code and the bytes written
+type responseWriter struct {
+	http.ResponseWriter
+	// status is the status code written (200 if the handler never calls WriteHeader)
+	status int
+	// bytes is the number of bytes of the body written
+	bytes int
+	// wroteHeader indicates if the header was already written
+	wroteHeader bool
+}
+
+// newResponseWriter wraps w
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (n int, err error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	n, err = rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return
+}
+
+// Unwrap returns the original writer (used by http.ResponseController)
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// countingBody is a request body that counts the bytes read
+type countingBody struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (b *countingBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	return
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// errors
+var (
+	ErrInvalidMoney = errors.New("money must be a valid amount")
+)
+
+// Money is an amount in cents, so it can be added without rounding errors
+// - it is written as a json number with two decimals
+type Money int64
+
+// NewMoney returns the amount of units and cents
+func NewMoney(units int64, cents int64) Money {
+	return Money(units*100 + cents)
+}
+
+// ParseMoney parses a decimal amount, rounding it to cents (half away from zero)
+func ParseMoney(s string) (m Money, err error) {
+	s = strings.TrimSpace(s)
+
+	// exponents are rare, parse them as a float
+	if strings.ContainsAny(s, "eE") {
+		f, errParse := strconv.ParseFloat(s, 64)
+		if errParse != nil || math.IsNaN(f) || math.Abs(f) >= math.MaxInt64/100 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+		}
+		return Money(math.Round(f * 100)), nil
+	}
+
+	// sign
+	negative := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	// units and decimals
+	units, decimals, _ := strings.Cut(digits, ".")
+	if units == "" && decimals == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	if units == "" {
+		units = "0"
+	}
+	decimals += "000"
+
+	u, errUnits := strconv.ParseInt(units, 10, 64)
+	c, errCents := strconv.ParseInt(decimals[:2], 10, 64)
+	r, errRound := strconv.ParseInt(decimals[2:3], 10, 64)
+	if errUnits != nil || errCents != nil || errRound != nil || u > math.MaxInt64/100-1 ||
+		strings.ContainsAny(units+decimals, "+-") {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	for _, ch := range decimals[3:] {
+		if ch < '0' || ch > '9' {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+		}
+	}
+
+	m = NewMoney(u, c)
+	if r >= 5 {
+		m++
+	}
+	if negative {
+		m = -m
+	}
+	return
+}
+
+// Mul returns the amount multiplied by a quantity
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// Float64 returns the amount in units
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String returns the amount with two decimals
+func (m Money) String() string {
+	sign := ""
+	abs := int64(m)
+	if abs < 0 {
+		sign = "-"
+		abs = -abs
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, abs/100, abs%100)
+}
+
+// MarshalJSON writes the amount as a json number with two decimals
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON reads the amount from a json number (or a string with a number)
+func (m *Money) UnmarshalJSON(b []byte) (err error) {
+	s := string(b)
+	if s == "null" {
+		return
+	}
+	if unquoted, errUnquote := strconv.Unquote(s); errUnquote == nil {
+		s = unquoted
+	}
+
+	*m, err = ParseMoney(s)
+	return
+}
@@ -0,0 +1,65 @@
+package internal_test
+
+import (
+	"app/internal"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	t.Run("success 01 - should parse amounts without rounding errors", func(t *testing.T) {
+		// arrange
+		cases := map[string]internal.Money{
+			"71.42":  7142,
+			"0.1":    10,
+			"100":    10000,
+			"-3.5":   -350,
+			"1.005":  101,
+			"0.995":  100,
+			"1e2":    10000,
+			".5":     50,
+			"352.79": 35279,
+		}
+
+		for input, expectedMoney := range cases {
+			// act
+			m, err := internal.ParseMoney(input)
+
+			// assert
+			require.NoError(t, err, input)
+			require.Equal(t, expectedMoney, m, input)
+		}
+	})
+
+	t.Run("failure 01 - invalid amounts", func(t *testing.T) {
+		for _, input := range []string{"", "abc", "1.2.3", "--1", "1.x", "1e300", "-1e300", "1e17"} {
+			// act
+			_, err := internal.ParseMoney(input)
+
+			// assert
+			require.ErrorIs(t, err, internal.ErrInvalidMoney, input)
+		}
+	})
+}
+
+func TestMoney_JSON(t *testing.T) {
+	t.Run("success 01 - should sum the inventory value exactly", func(t *testing.T) {
+		// arrange
+		var prices []internal.Money
+		err := json.Unmarshal([]byte(`[0.1, 0.2, 0.3]`), &prices)
+		require.NoError(t, err)
+
+		// act
+		var total internal.Money
+		for _, p := range prices {
+			total += p.Mul(1)
+		}
+		b, err := json.Marshal(total)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, "0.60", string(b))
+	})
+}
@@ -0,0 +1,35 @@
+package internal
+
+import "context"
+
+// Principal is a struct that represents who makes a request
+type Principal struct {
+	// Subject identifies the client or user (e.g. the name of an api key)
+	Subject string
+	// Scopes are the permissions granted (e.g. products:write)
+	Scopes []string
+}
+
+// HasScope checks if the principal was granted a scope
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// principalKey is the key of the principal in a context
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx that carries the principal of the request
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the request carried by ctx
+func PrincipalFromContext(ctx context.Context) (principal Principal, ok bool) {
+	principal, ok = ctx.Value(principalKey{}).(Principal)
+	return
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"errors"
+)
+
+// errors
+var (
+	ErrInvalidQuery = errors.New("invalid query")
+)
+
+// fields a list of products can be sorted by
+const (
+	SortById          = "id"
+	SortByName        = "name"
+	SortByQuantity    = "quantity"
+	SortByCodeValue   = "code_value"
+	SortByIsPublished = "is_published"
+	SortByExpiration  = "expiration"
+	SortByPrice       = "price"
+)
+
+// ProductQuery is a struct that represents the criteria to filter, sort and paginate a list of products
+// - nil / zero fields are not applied
+type ProductQuery struct {
+	// Name filters the products whose name contains it (case insensitive)
+	Name string
+	// IsPublished filters the products by their published state
+	IsPublished *bool
+	// PriceMin and PriceMax filter the products by price (inclusive)
+	PriceMin *Money
+	PriceMax *Money
+	// QuantityMin and QuantityMax filter the products by quantity (inclusive)
+	QuantityMin *int
+	QuantityMax *int
+	// ExpirationBefore and ExpirationAfter filter the products by expiration date (exclusive)
+	ExpirationBefore *Date
+	ExpirationAfter  *Date
+
+	// SortBy is the field the products are sorted by (by default id)
+	SortBy string
+	// SortDesc sorts the products in descending order
+	SortDesc bool
+
+	// Limit is the maximum number of products returned (0 means all)
+	Limit int
+	// Offset is the number of products skipped
+	Offset int
+}
+
+// ProductPage is a struct that represents a page of a list of products
+type ProductPage struct {
+	// Products are the products of the page
+	Products []Product
+	// Total is the number of products that match the query, without pagination
+	Total int
+	// Limit is the limit used
+	Limit int
+	// Offset is the offset used
+	Offset int
+}
+
+// ProductSearch is a struct that represents the combined predicates of a product search
+// - nil / zero fields are not applied
+type ProductSearch struct {
+	// Q is a text searched in the names of the products (fuzzy), the results are ranked by relevance
+	Q string
+	// PriceGt and PriceLt filter the products priced above / below a threshold (exclusive)
+	PriceGt *Money
+	PriceLt *Money
+	// ExpiringWithinDays filters the products that are not expired and expire in the next N days
+	ExpiringWithinDays *int
+	// PublishedOnly filters the published products
+	PublishedOnly bool
+}
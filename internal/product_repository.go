@@ -6,18 +6,29 @@ import "errors"
 var (
 	ErrRepeatedCode    = errors.New("code value must be unique")
 	ErrProductNotFound = errors.New("product not found")
+	ErrProductStorage  = errors.New("product storage failure")
+	ErrVersionConflict = errors.New("product was modified by another request")
 )
 
+// AnyVersion is the expected version that skips the check of the version in Update and Delete
+const AnyVersion = -1
+
 // product repository is an interface that defines the methods that the repository must implement
 type ProductRepository interface { // maybe i should change this functions later
 	// GetAll returns the list of products from the repository
 	GetAll() (products []Product)
 	// GetById returns a product by id from the repository
 	GetById(id int) (product Product, err error)
-	// Create creates a product in the repository
+	// GetByCode returns a product by code value from the repository
+	GetByCode(code string) (product Product, err error)
+	// SearchByName returns the products whose name matches the query, most relevant first
+	SearchByName(query string) (products []Product)
+	// Create creates a product in the repository, setting its id and its version to 1
 	Create(product *Product) (err error)
-	// Update updates a product in the repository
-	Update(product *Product) (err error)
+	// Update updates a product in the repository, setting its version to the stored one plus 1
+	// - it returns ErrVersionConflict if the stored version is not expectedVersion (unless it is AnyVersion)
+	Update(product *Product, expectedVersion int) (err error)
 	// Delete delete a product from the repository
-	Delete(id int) (err error)
+	// - it returns ErrVersionConflict if the stored version is not expectedVersion (unless it is AnyVersion)
+	Delete(id int, expectedVersion int) (err error)
 }
@@ -1,6 +1,9 @@
 package internal
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 // errors
 var (
@@ -12,15 +15,26 @@ var (
 // - business logic
 // - validation
 // - external services (e.g. apis, databases, etc.)
+// - ctx carries the values of the request (e.g. its id) used to log
 type ProductService interface {
 	// GetAll gets all movies
-	GetAll() (products []Product)
+	GetAll(ctx context.Context) (products []Product)
+	// Query gets the products that match the query, sorted and paginated
+	Query(ctx context.Context, query ProductQuery) (page ProductPage, err error)
+	// Search gets the products that match all the predicates of the search, ranked by relevance or sorted by id
+	Search(ctx context.Context, search ProductSearch) (products []Product, err error)
+	// Stats gets the figures of the inventory, counting the products expiring within the days given
+	Stats(ctx context.Context, expiringWithinDays int) (stats ProductStats)
 	// GetById gets a movie by id
-	GetById(id int) (product Product, err error)
+	GetById(ctx context.Context, id int) (product Product, err error)
+	// GetByCode gets a product by code value
+	GetByCode(ctx context.Context, code string) (product Product, err error)
+	// Validate validates a product, returning a *ValidationError with all the violations
+	Validate(product *Product) (err error)
 	// Create creates a product
-	Create(product *Product) (err error)
-	// Update updates a product
-	Update(product *Product) (err error)
-	// Delete deletes a product
-	Delete(id int) (err error)
+	Create(ctx context.Context, product *Product) (err error)
+	// Update updates a product, if it is still the expected version (AnyVersion skips the check)
+	Update(ctx context.Context, product *Product, expectedVersion int) (err error)
+	// Delete deletes a product, if it is still the expected version (AnyVersion skips the check)
+	Delete(ctx context.Context, id int, expectedVersion int) (err error)
 }
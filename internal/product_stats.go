@@ -0,0 +1,15 @@
+package internal
+
+// ProductStats is a struct that represents the figures of the inventory
+type ProductStats struct {
+	// Count is the number of products
+	Count int
+	// Published is the number of published products
+	Published int
+	// Stock is the sum of the quantities of the products
+	Stock int
+	// Value is the sum of the price of the products by their quantity
+	Value Money
+	// Expiring is the number of products expiring from today to the days given
+	Expiring int
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// in this file, i handle the inverted index used to search the products by name
+
+// scores of a match between a token of the query and a token of a name
+const (
+	scoreExact  = 3
+	scorePrefix = 2
+	scoreTypo   = 1
+)
+
+// newNameIndex returns an empty nameIndex
+func newNameIndex() *nameIndex {
+	return &nameIndex{
+		postings: make(map[string]map[int]struct{}),
+		tokens:   make(map[int][]string),
+	}
+}
+
+// nameIndex is an inverted index from the tokens of the names to the ids of the products
+// - it is not safe for concurrent use, the owner must synchronize it
+type nameIndex struct {
+	// postings are the ids of the products that contain each token
+	postings map[string]map[int]struct{}
+	// tokens are the tokens of each product, used to remove it
+	tokens map[int][]string
+}
+
+// add indexes the name of a product, replacing the previous one
+func (ix *nameIndex) add(id int, name string) {
+	ix.remove(id)
+
+	tokens := tokenize(name)
+	for _, t := range tokens {
+		ids, ok := ix.postings[t]
+		if !ok {
+			ids = make(map[int]struct{})
+			ix.postings[t] = ids
+		}
+		ids[id] = struct{}{}
+	}
+	ix.tokens[id] = tokens
+}
+
+// remove removes a product from the index
+func (ix *nameIndex) remove(id int) {
+	for _, t := range ix.tokens[id] {
+		delete(ix.postings[t], id)
+		if len(ix.postings[t]) == 0 {
+			delete(ix.postings, t)
+		}
+	}
+	delete(ix.tokens, id)
+}
+
+// search returns the ids of the products whose name matches the query, most relevant first
+// - a token of the query matches a token of the name exactly, as a prefix or with a typo
+// - products matching any token of the query are returned: the ones matching more tokens rank first,
+// then the ones with better matches
+func (ix *nameIndex) search(query string) (ids []int) {
+	type result struct {
+		matched int
+		score   int
+	}
+	results := make(map[int]*result)
+
+	for _, qt := range tokenize(query) {
+		// best score of the token for each product
+		best := make(map[int]int)
+		for t, postings := range ix.postings {
+			score := matchToken(qt, t)
+			if score == 0 {
+				continue
+			}
+			for id := range postings {
+				best[id] = max(best[id], score)
+			}
+		}
+
+		for id, score := range best {
+			r, ok := results[id]
+			if !ok {
+				r = &result{}
+				results[id] = r
+			}
+			r.matched++
+			r.score += score
+		}
+	}
+
+	ids = make([]int, 0, len(results))
+	for id := range results {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := results[ids[i]], results[ids[j]]
+		if a.matched != b.matched {
+			return a.matched > b.matched
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return ids[i] < ids[j]
+	})
+	return
+}
+
+// matchToken returns the score of a token of the query against a token of a name (0 if it does not match)
+// - the distance is only computed for tokens whose length is within the typos tolerated,
+// as the distance is at least the difference of the lengths
+func matchToken(query, token string) int {
+	switch {
+	case query == token:
+		return scoreExact
+	case strings.HasPrefix(token, query):
+		return scorePrefix
+	}
+
+	tolerated := typos(query)
+	if tolerated == 0 {
+		return 0
+	}
+	if diff := utf8.RuneCountInString(query) - utf8.RuneCountInString(token); diff > tolerated || -diff > tolerated {
+		return 0
+	}
+	if distance(query, token) <= tolerated {
+		return scoreTypo
+	}
+	return 0
+}
+
+// typos returns the number of typos tolerated for a token of the query
+func typos(token string) int {
+	switch n := len([]rune(token)); {
+	case n <= 3:
+		return 0
+	case n <= 7:
+		return 1
+	}
+	return 2
+}
+
+// distance returns the edit distance between a and b, counting a transposition as one edit
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// tokenize splits a text into lower case tokens without diacritics
+func tokenize(text string) (tokens []string) {
+	fields := strings.FieldsFunc(fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	// remove duplicated tokens
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			tokens = append(tokens, f)
+		}
+	}
+	return
+}
+
+// diacritics maps the lower case latin letters with diacritics to their base letter
+var diacritics = map[rune]rune{
+	'á': 'a', 'à': 'a', 'â': 'a', 'ä': 'a', 'ã': 'a', 'å': 'a',
+	'é': 'e', 'è': 'e', 'ê': 'e', 'ë': 'e',
+	'í': 'i', 'ì': 'i', 'î': 'i', 'ï': 'i',
+	'ó': 'o', 'ò': 'o', 'ô': 'o', 'ö': 'o', 'õ': 'o', 'ø': 'o',
+	'ú': 'u', 'ù': 'u', 'û': 'u', 'ü': 'u',
+	'ñ': 'n', 'ç': 'c', 'ý': 'y', 'ÿ': 'y',
+}
+
+// fold returns the text in lower case and without diacritics
+func fold(text string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if base, ok := diacritics[r]; ok {
+			return base
+		}
+		return r
+	}, text)
+}
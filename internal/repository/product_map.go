@@ -2,13 +2,22 @@ package repository
 
 import (
 	"app/internal"
+	"fmt"
+	"sync"
 )
 
 // in this file, i only handle what is related directly to the products
 
 // this is the handler for the products
+// - it is safe for concurrent use: readers share the lock and writers take it exclusively
 type ProductsMap struct {
-	data   map[int]internal.Product
+	// mu protects data, codes and lastID
+	mu   sync.RWMutex
+	data map[int]internal.Product
+	// codes is an index of the id of the products by code value
+	codes map[string]int
+	// names is an inverted index of the names of the products
+	names  *nameIndex
 	lastID int
 }
 
@@ -17,9 +26,13 @@ func NewProductsMap(db []internal.Product) *ProductsMap {
 
 	// convert the slice into a map
 	productMap := make(map[int]internal.Product)
+	codes := make(map[string]int)
+	names := newNameIndex()
 	lastID := 0
 	for _, p := range db {
 		productMap[p.Id] = p
+		codes[p.Code_value] = p.Id
+		names.add(p.Id, p.Name)
 		if p.Id > lastID {
 			lastID = p.Id
 		}
@@ -27,18 +40,82 @@ func NewProductsMap(db []internal.Product) *ProductsMap {
 
 	return &ProductsMap{
 		data:   productMap,
+		codes:  codes,
+		names:  names,
 		lastID: lastID,
 	}
 }
 
 func (ph *ProductsMap) GetAll() (products []internal.Product) {
+	ph.mu.RLock()
+	defer ph.mu.RUnlock()
+
+	return ph.getAll()
+}
+
+// Count returns the number of products without copying them
+func (ph *ProductsMap) Count() int {
+	ph.mu.RLock()
+	defer ph.mu.RUnlock()
+
+	return len(ph.data)
+}
+
+func (ph *ProductsMap) GetById(id int) (product internal.Product, err error) {
+	ph.mu.RLock()
+	defer ph.mu.RUnlock()
+
+	return ph.getById(id)
+}
+
+func (ph *ProductsMap) GetByCode(code string) (product internal.Product, err error) {
+	ph.mu.RLock()
+	defer ph.mu.RUnlock()
+
+	return ph.getByCode(code)
+}
+
+func (ph *ProductsMap) SearchByName(query string) (products []internal.Product) {
+	ph.mu.RLock()
+	defer ph.mu.RUnlock()
+
+	for _, id := range ph.names.search(query) {
+		products = append(products, ph.data[id])
+	}
+	return
+}
+
+func (ph *ProductsMap) Create(product *internal.Product) (err error) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+
+	return ph.create(product)
+}
+
+func (ph *ProductsMap) Update(product *internal.Product, expectedVersion int) (err error) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+
+	return ph.update(product, expectedVersion)
+}
+
+func (ph *ProductsMap) Delete(id int, expectedVersion int) (err error) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+
+	return ph.delete(id, expectedVersion)
+}
+
+// the following methods must be called with mu held
+
+func (ph *ProductsMap) getAll() (products []internal.Product) {
 	for _, p := range ph.data {
 		products = append(products, p)
 	}
 	return
 }
 
-func (ph *ProductsMap) GetById(id int) (product internal.Product, err error) {
+func (ph *ProductsMap) getById(id int) (product internal.Product, err error) {
 	product, ok := ph.data[id]
 	if !ok {
 		err = internal.ErrProductNotFound
@@ -46,36 +123,83 @@ func (ph *ProductsMap) GetById(id int) (product internal.Product, err error) {
 	return
 }
 
-func (ph *ProductsMap) Create(product *internal.Product) (err error) {
+func (ph *ProductsMap) getByCode(code string) (product internal.Product, err error) {
+	id, ok := ph.codes[code]
+	if !ok {
+		err = internal.ErrProductNotFound
+		return
+	}
+	return ph.data[id], nil
+}
+
+func (ph *ProductsMap) create(product *internal.Product) (err error) {
 	// code value must be unique
-	for _, p := range (*ph).data {
-		if p.Code_value == product.Code_value {
-			return internal.ErrRepeatedCode
-		}
+	if _, ok := ph.codes[product.Code_value]; ok {
+		return internal.ErrRepeatedCode
 	}
 
 	ph.lastID++
 	product.Id = ph.lastID
-	ph.data[product.Id] = *product
+	product.Version = 1
+	ph.put(*product)
 	return
 }
 
-func (ph *ProductsMap) Update(product *internal.Product) (err error) {
-	_, ok := ph.data[product.Id]
+func (ph *ProductsMap) update(product *internal.Product, expectedVersion int) (err error) {
+	previous, ok := ph.data[product.Id]
 	if !ok {
 		return internal.ErrProductNotFound
 	}
+	if err = checkVersion(previous, expectedVersion); err != nil {
+		return
+	}
+
+	// code value must be unique (it can be kept by the same product)
+	if id, ok := ph.codes[product.Code_value]; ok && id != product.Id {
+		return internal.ErrRepeatedCode
+	}
 
-	ph.data[product.Id] = *product
+	product.Version = previous.Version + 1
+	ph.put(*product)
 	return
 }
 
-func (ph *ProductsMap) Delete(id int) (err error) {
-	_, ok := ph.data[id]
+func (ph *ProductsMap) delete(id int, expectedVersion int) (err error) {
+	previous, ok := ph.data[id]
 	if !ok {
 		return internal.ErrProductNotFound
 	}
+	if err = checkVersion(previous, expectedVersion); err != nil {
+		return
+	}
 
-	delete(ph.data, id)
+	ph.remove(id)
 	return
 }
+
+// put stores a product keeping the indexes in sync
+func (ph *ProductsMap) put(product internal.Product) {
+	if previous, ok := ph.data[product.Id]; ok {
+		delete(ph.codes, previous.Code_value)
+	}
+	ph.data[product.Id] = product
+	ph.codes[product.Code_value] = product.Id
+	ph.names.add(product.Id, product.Name)
+}
+
+// remove deletes a product keeping the indexes in sync
+func (ph *ProductsMap) remove(id int) {
+	if previous, ok := ph.data[id]; ok {
+		delete(ph.codes, previous.Code_value)
+	}
+	delete(ph.data, id)
+	ph.names.remove(id)
+}
+
+// checkVersion returns ErrVersionConflict if the stored product is not the expected version
+func checkVersion(stored internal.Product, expectedVersion int) (err error) {
+	if expectedVersion == internal.AnyVersion || stored.Version == expectedVersion {
+		return
+	}
+	return fmt.Errorf("%w: expected version %d, current version is %d", internal.ErrVersionConflict, expectedVersion, stored.Version)
+}
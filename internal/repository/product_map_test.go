@@ -0,0 +1,222 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductsMap_Create(t *testing.T) {
+	t.Run("success 01 - concurrent creations get unique ids", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap(nil)
+		n := 50
+
+		// act
+		var wg sync.WaitGroup
+		errs := make([]error, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				product := internal.Product{Name: "product", Code_value: fmt.Sprintf("code%d", i)}
+				errs[i] = rp.Create(&product)
+				_ = rp.GetAll()
+			}(i)
+		}
+		wg.Wait()
+
+		// assert
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+		ids := make(map[int]bool)
+		for _, p := range rp.GetAll() {
+			ids[p.Id] = true
+		}
+		require.Len(t, ids, n)
+	})
+
+	t.Run("failure 01 - concurrent creations with the same code, only one succeeds", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap(nil)
+		n := 50
+
+		// act
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		created, repeated := 0, 0
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				product := internal.Product{Name: "product", Code_value: "code"}
+				err := rp.Create(&product)
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					created++
+				case errors.Is(err, internal.ErrRepeatedCode):
+					repeated++
+				}
+			}()
+		}
+		wg.Wait()
+
+		// assert
+		require.Equal(t, 1, created)
+		require.Equal(t, n-1, repeated)
+	})
+}
+
+func TestProductsMap_Update(t *testing.T) {
+	t.Run("success 01 - should change the code value of the product", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Code_value: "code1"}})
+
+		// act
+		err := rp.Update(&internal.Product{Id: 1, Code_value: "code2"}, internal.AnyVersion)
+
+		// assert
+		require.NoError(t, err)
+		_, err = rp.GetByCode("code1")
+		require.ErrorIs(t, err, internal.ErrProductNotFound)
+		product, err := rp.GetByCode("code2")
+		require.NoError(t, err)
+		require.Equal(t, 1, product.Id)
+	})
+
+	t.Run("success 02 - should increment the version of the product", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Code_value: "code1", Version: 3}})
+
+		// act
+		product := internal.Product{Id: 1, Code_value: "code1", Version: 1}
+		err := rp.Update(&product, internal.AnyVersion)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 4, product.Version)
+		stored, err := rp.GetById(1)
+		require.NoError(t, err)
+		require.Equal(t, 4, stored.Version)
+	})
+
+	t.Run("failure 01 - code value of another product", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Code_value: "code1"}, {Id: 2, Code_value: "code2"}})
+
+		// act
+		err := rp.Update(&internal.Product{Id: 2, Code_value: "code1"}, internal.AnyVersion)
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrRepeatedCode)
+	})
+
+	t.Run("failure 02 - version of the product is not the expected", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Name: "name1", Code_value: "code1", Version: 3}})
+
+		// act
+		err := rp.Update(&internal.Product{Id: 1, Name: "name2", Code_value: "code1"}, 2)
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrVersionConflict)
+		stored, err := rp.GetById(1)
+		require.NoError(t, err)
+		require.Equal(t, "name1", stored.Name)
+		require.Equal(t, 3, stored.Version)
+	})
+}
+
+func TestProductsMap_Delete(t *testing.T) {
+	t.Run("success 01 - the code value can be reused", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Code_value: "code1"}})
+
+		// act
+		err := rp.Delete(1, internal.AnyVersion)
+
+		// assert
+		require.NoError(t, err)
+		require.NoError(t, rp.Create(&internal.Product{Code_value: "code1"}))
+	})
+
+	t.Run("success 02 - version of the product is the expected", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Code_value: "code1", Version: 3}})
+
+		// act
+		err := rp.Delete(1, 3)
+
+		// assert
+		require.NoError(t, err)
+		_, err = rp.GetById(1)
+		require.ErrorIs(t, err, internal.ErrProductNotFound)
+	})
+
+	t.Run("failure 01 - version of the product is not the expected", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap([]internal.Product{{Id: 1, Code_value: "code1", Version: 3}})
+
+		// act
+		err := rp.Delete(1, 2)
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrVersionConflict)
+		_, err = rp.GetById(1)
+		require.NoError(t, err)
+	})
+}
+
+func TestProductsMap_SearchByName(t *testing.T) {
+	// arrange
+	db := []internal.Product{
+		{Id: 1, Name: "Wine - Red Oakridge Merlot", Code_value: "1"},
+		{Id: 2, Name: "Wine - White, Colombard", Code_value: "2"},
+		{Id: 3, Name: "Crème Brûlée", Code_value: "3"},
+		{Id: 4, Name: "Cookie - Oatmeal", Code_value: "4"},
+	}
+
+	t.Run("success 01 - should rank the products by relevance", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap(db)
+
+		// act
+		products := rp.SearchByName("wine merlot")
+
+		// assert
+		require.Equal(t, []internal.Product{db[0], db[1]}, products)
+	})
+
+	t.Run("success 02 - should match prefixes, typos and diacritics", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap(db)
+
+		// act / assert
+		require.Equal(t, []internal.Product{db[0]}, rp.SearchByName("oakr"))
+		require.Equal(t, []internal.Product{db[0]}, rp.SearchByName("Merlto"))
+		require.Equal(t, []internal.Product{db[2]}, rp.SearchByName("creme brulee"))
+		require.Empty(t, rp.SearchByName("pineapple"))
+	})
+
+	t.Run("success 03 - should stay in sync with the mutations", func(t *testing.T) {
+		// arrange
+		rp := repository.NewProductsMap(db)
+
+		// act
+		require.NoError(t, rp.Update(&internal.Product{Id: 4, Name: "Cookie - Chocolate", Code_value: "4"}, internal.AnyVersion))
+		require.NoError(t, rp.Delete(3, internal.AnyVersion))
+
+		// assert
+		require.Empty(t, rp.SearchByName("oatmeal"))
+		require.Empty(t, rp.SearchByName("creme"))
+		require.Equal(t, []internal.Product{{Id: 4, Name: "Cookie - Chocolate", Code_value: "4", Version: 1}}, rp.SearchByName("chocolate"))
+	})
+}
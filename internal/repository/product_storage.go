@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"app/internal"
+	"app/internal/storage"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// in this file, i handle the persistence of the products map in a storage
+
+// NewProductsStorage returns a new ProductsStorage instance, filled with the products read from the storage
+func NewProductsStorage(st storage.ProductStorageJSON) (rp *ProductsStorage, err error) {
+	// load the products from the storage
+	db, err := st.ReadAll()
+	if err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrProductStorage, err)
+		return
+	}
+
+	rp = &ProductsStorage{
+		ProductsMap: NewProductsMap(db),
+		st:          st,
+	}
+	return
+}
+
+// ProductsStorage is a decorator of ProductsMap that writes every mutation through the storage
+// - if the storage fails, the change in memory is rolled back (as well as the id and version given to the product)
+// - the lock of the map is held while persisting, so the storage sees the changes in order
+type ProductsStorage struct {
+	*ProductsMap
+	// st is the storage where the products are persisted
+	st storage.ProductStorageJSON
+}
+
+func (ps *ProductsStorage) Create(product *internal.Product) (err error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	input, lastID := *product, ps.lastID
+	if err = ps.create(product); err != nil {
+		return
+	}
+
+	// persist the product
+	if err = ps.persistPut(*product); err != nil {
+		// rollback, so a retry gets the same id
+		ps.remove(product.Id)
+		ps.lastID = lastID
+		*product = input
+		return
+	}
+	return
+}
+
+func (ps *ProductsStorage) Update(product *internal.Product, expectedVersion int) (err error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	previous, err := ps.getById(product.Id)
+	if err != nil {
+		return
+	}
+
+	input := *product
+	if err = ps.update(product, expectedVersion); err != nil {
+		return
+	}
+
+	// persist the product
+	if err = ps.persistPut(*product); err != nil {
+		// rollback
+		ps.put(previous)
+		*product = input
+		return
+	}
+	return
+}
+
+func (ps *ProductsStorage) Delete(id int, expectedVersion int) (err error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	previous, err := ps.getById(id)
+	if err != nil {
+		return
+	}
+
+	if err = ps.delete(id, expectedVersion); err != nil {
+		return
+	}
+
+	// persist the deletion
+	if err = ps.persistRemove(id); err != nil {
+		// rollback
+		ps.put(previous)
+		return
+	}
+	return
+}
+
+// Check returns an error if the storage cannot persist the changes
+// - storages that cannot be checked are considered writable
+func (ps *ProductsStorage) Check() (err error) {
+	st, ok := ps.st.(storage.ProductStorageChecker)
+	if !ok {
+		return
+	}
+
+	if err = st.Check(); err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrProductStorage, err)
+	}
+	return
+}
+
+// Close compacts an incremental storage and closes the storage (if it can be closed)
+// - it is the final flush on shutdown, so a journal leaves a compacted snapshot
+// - the rest of storages are already written through on every change, so they are not rewritten
+// (rewriting them would replace their backup with a copy of the same products)
+// - the storage is closed even if the flush fails (the changes were already written through)
+func (ps *ProductsStorage) Close() (err error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.st.(storage.ProductStorageIncremental); ok {
+		err = ps.persist()
+	}
+
+	if closer, ok := ps.st.(io.Closer); ok {
+		if errClose := closer.Close(); errClose != nil {
+			err = errors.Join(err, fmt.Errorf("%w: %w", internal.ErrProductStorage, errClose))
+		}
+	}
+	return
+}
+
+// persistPut persists a created or updated product
+// - incremental storages only store the change, the rest rewrite all the products
+func (ps *ProductsStorage) persistPut(product internal.Product) (err error) {
+	st, ok := ps.st.(storage.ProductStorageIncremental)
+	if !ok {
+		return ps.persist()
+	}
+
+	if err = st.Put(product); err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrProductStorage, err)
+	}
+	return
+}
+
+// persistRemove persists a deleted product
+func (ps *ProductsStorage) persistRemove(id int) (err error) {
+	st, ok := ps.st.(storage.ProductStorageIncremental)
+	if !ok {
+		return ps.persist()
+	}
+
+	if err = st.Remove(id); err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrProductStorage, err)
+	}
+	return
+}
+
+// persist writes all the products of the map in the storage
+func (ps *ProductsStorage) persist() (err error) {
+	// keep the file ordered by id (and as an empty list instead of null)
+	products := ps.getAll()
+	if products == nil {
+		products = []internal.Product{}
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].Id < products[j].Id })
+
+	if err = ps.st.WriteAll(products); err != nil {
+		err = fmt.Errorf("%w: %w", internal.ErrProductStorage, err)
+	}
+	return
+}
@@ -0,0 +1,211 @@
+package repository_test
+
+import (
+	"app/internal"
+	"app/internal/repository"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// StorageStub is a stub of storage.ProductStorageJSON
+type StorageStub struct {
+	products []internal.Product
+	errRead  error
+	errWrite error
+	writes   int
+	closed   bool
+}
+
+func (s *StorageStub) ReadAll() (products []internal.Product, err error) {
+	return s.products, s.errRead
+}
+
+func (s *StorageStub) WriteAll(products []internal.Product) (err error) {
+	s.writes++
+	if s.errWrite != nil {
+		return s.errWrite
+	}
+	s.products = products
+	return
+}
+
+func (s *StorageStub) Close() (err error) {
+	s.closed = true
+	return
+}
+
+// StorageIncrementalStub is a StorageStub that persists a single change
+type StorageIncrementalStub struct {
+	StorageStub
+	changes int
+}
+
+func (s *StorageIncrementalStub) Put(product internal.Product) (err error) {
+	s.changes++
+	return s.errWrite
+}
+
+func (s *StorageIncrementalStub) Remove(id int) (err error) {
+	s.changes++
+	return s.errWrite
+}
+
+func TestProductsStorage_Close(t *testing.T) {
+	t.Run("success 01 - should close the storage without rewriting it", func(t *testing.T) {
+		// arrange
+		product := internal.Product{Id: 1, Name: "product 1", Quantity: 1, Code_value: "code1", Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(1, 10)}
+		st := &StorageStub{products: []internal.Product{product}}
+		rp, err := repository.NewProductsStorage(st)
+		require.NoError(t, err)
+
+		// act
+		err = rp.Close()
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 0, st.writes)
+		require.True(t, st.closed)
+	})
+
+	t.Run("success 02 - should compact an incremental storage and close it", func(t *testing.T) {
+		// arrange
+		product := internal.Product{Id: 1, Name: "product 1", Quantity: 1, Code_value: "code1", Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(1, 10)}
+		st := &StorageIncrementalStub{StorageStub: StorageStub{products: []internal.Product{product}}}
+		rp, err := repository.NewProductsStorage(st)
+		require.NoError(t, err)
+
+		// act
+		err = rp.Close()
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 1, st.writes)
+		require.Equal(t, []internal.Product{product}, st.products)
+		require.True(t, st.closed)
+	})
+
+	t.Run("failure 01 - storage fails, it is closed anyway", func(t *testing.T) {
+		// arrange
+		st := &StorageIncrementalStub{StorageStub: StorageStub{errWrite: errors.New("disk full")}}
+		rp, err := repository.NewProductsStorage(st)
+		require.NoError(t, err)
+
+		// act
+		err = rp.Close()
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrProductStorage)
+		require.True(t, st.closed)
+	})
+}
+
+func TestProductsStorage_Create(t *testing.T) {
+	t.Run("success 01 - should persist the created product", func(t *testing.T) {
+		// arrange
+		st := &StorageStub{}
+		rp, err := repository.NewProductsStorage(st)
+		require.NoError(t, err)
+
+		// act
+		product := internal.Product{Name: "product 1", Quantity: 1, Code_value: "code1", Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(1, 10)}
+		err = rp.Create(&product)
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, 1, st.writes)
+		require.Equal(t, []internal.Product{product}, st.products)
+	})
+
+	t.Run("failure 01 - storage fails, the product is rolled back", func(t *testing.T) {
+		// arrange
+		errDisk := errors.New("disk full")
+		st := &StorageStub{errWrite: errDisk}
+		rp, err := repository.NewProductsStorage(st)
+		require.NoError(t, err)
+
+		// act
+		input := internal.Product{Name: "product 1", Quantity: 1, Code_value: "code1", Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(1, 10)}
+		product := input
+		err = rp.Create(&product)
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrProductStorage)
+		require.ErrorIs(t, err, errDisk)
+		require.Empty(t, rp.GetAll())
+		require.Equal(t, input, product)
+		// a retry gets the id that failed
+		st.errWrite = nil
+		require.NoError(t, rp.Create(&product))
+		require.Equal(t, 1, product.Id)
+	})
+}
+
+func TestProductsStorage_Update(t *testing.T) {
+	t.Run("failure 01 - storage fails, the previous product is restored", func(t *testing.T) {
+		// arrange
+		previous := internal.Product{Id: 1, Name: "product 1", Quantity: 1, Code_value: "code1", Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(1, 10)}
+		st := &StorageStub{products: []internal.Product{previous}, errWrite: errors.New("disk full")}
+		rp, err := repository.NewProductsStorage(st)
+		require.NoError(t, err)
+
+		// act
+		updated := previous
+		updated.Name = "product 2"
+		err = rp.Update(&updated, internal.AnyVersion)
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrProductStorage)
+		require.Equal(t, 0, updated.Version)
+		product, err := rp.GetById(1)
+		require.NoError(t, err)
+		require.Equal(t, previous, product)
+	})
+}
+
+func TestProductsStorage_Delete(t *testing.T) {
+	t.Run("success 01 - should persist the deletion", func(t *testing.T) {
+		// arrange
+		st := &StorageStub{products: []internal.Product{{Id: 1, Name: "product 1", Code_value: "code1"}}}
+		rp, err := repository.NewProductsStorage(st)
+		require.NoError(t, err)
+
+		// act
+		err = rp.Delete(1, internal.AnyVersion)
+
+		// assert
+		require.NoError(t, err)
+		require.Empty(t, st.products)
+	})
+
+	t.Run("failure 01 - storage fails, the product is restored", func(t *testing.T) {
+		// arrange
+		previous := internal.Product{Id: 1, Name: "product 1", Code_value: "code1"}
+		st := &StorageStub{products: []internal.Product{previous}, errWrite: errors.New("disk full")}
+		rp, err := repository.NewProductsStorage(st)
+		require.NoError(t, err)
+
+		// act
+		err = rp.Delete(1, internal.AnyVersion)
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrProductStorage)
+		product, err := rp.GetById(1)
+		require.NoError(t, err)
+		require.Equal(t, previous, product)
+	})
+}
+
+func TestNewProductsStorage(t *testing.T) {
+	t.Run("failure 01 - storage cannot be read", func(t *testing.T) {
+		// arrange
+		st := &StorageStub{errRead: errors.New("no such file")}
+
+		// act
+		_, err := repository.NewProductsStorage(st)
+
+		// assert
+		require.ErrorIs(t, err, internal.ErrProductStorage)
+	})
+}
@@ -0,0 +1,17 @@
+package internal
+
+import "context"
+
+// requestIDKey is the key of the request id in a context
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx that carries the id of the request
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the id of the request carried by ctx (empty if there is none)
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -2,7 +2,10 @@ package service
 
 import (
 	"app/internal"
-	"time"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
 )
 
 // in this file i only handle what is related with the rules
@@ -22,16 +25,25 @@ type MovieDefault struct {
 	// ... (weather api, etc.)
 }
 
-func (d *MovieDefault) GetAll() (products []internal.Product) {
+func (d *MovieDefault) GetAll(ctx context.Context) (products []internal.Product) {
 	return d.rp.GetAll()
 }
 
-func (d *MovieDefault) GetById(id int) (product internal.Product, err error) {
+func (d *MovieDefault) GetById(ctx context.Context, id int) (product internal.Product, err error) {
 	product, err = (*d).rp.GetById(id)
 	return
 }
 
-func (d *MovieDefault) Create(product *internal.Product) (err error) {
+func (d *MovieDefault) GetByCode(ctx context.Context, code string) (product internal.Product, err error) {
+	product, err = (*d).rp.GetByCode(code)
+	return
+}
+
+func (d *MovieDefault) Validate(product *internal.Product) (err error) {
+	return ValidateProduct(product)
+}
+
+func (d *MovieDefault) Create(ctx context.Context, product *internal.Product) (err error) {
 	// validate the product
 	if err = ValidateProduct(product); err != nil {
 		return
@@ -39,39 +51,87 @@ func (d *MovieDefault) Create(product *internal.Product) (err error) {
 
 	// here i must call the repository to create the product
 	err = (*d).rp.Create(product)
+	err = uniquenessError(err)
+	d.logMutation(ctx, "product created", product.Id, err)
 	return
 }
 
-func (d *MovieDefault) Update(product *internal.Product) (err error) {
+func (d *MovieDefault) Update(ctx context.Context, product *internal.Product, expectedVersion int) (err error) {
 	// validate the product
 	if err = ValidateProduct(product); err != nil {
 		return
 	}
 
 	// update product
-	err = (*d).rp.Update(product)
+	err = (*d).rp.Update(product, expectedVersion)
+	err = uniquenessError(err)
+	d.logMutation(ctx, "product updated", product.Id, err)
 
 	return
 }
 
-func (d *MovieDefault) Delete(id int) (err error) {
+func (d *MovieDefault) Delete(ctx context.Context, id int, expectedVersion int) (err error) {
 	// here i must call the repository to delete the product
-	err = (*d).rp.Delete(id)
+	err = (*d).rp.Delete(id, expectedVersion)
+	d.logMutation(ctx, "product deleted", id, err)
 	return
 }
 
+// logMutation logs the result of a change in the repository, with the request and the principal that made it
+// - expected errors (e.g. not found, validation) are not logged
+func (d *MovieDefault) logMutation(ctx context.Context, msg string, id int, err error) {
+	attrs := []slog.Attr{
+		slog.String("request_id", internal.RequestIDFromContext(ctx)),
+		slog.Int("product_id", id),
+	}
+	if principal, ok := internal.PrincipalFromContext(ctx); ok {
+		attrs = append(attrs, slog.String("subject", principal.Subject))
+	}
+
+	switch {
+	case err == nil:
+		slog.Default().LogAttrs(ctx, slog.LevelInfo, msg, attrs...)
+	case errors.Is(err, internal.ErrProductStorage):
+		attrs = append(attrs, slog.String("error", err.Error()))
+		slog.Default().LogAttrs(ctx, slog.LevelError, msg+" failed", attrs...)
+	}
+}
+
+// ValidateProduct validates the fields of a product
+// - name, code value and expiration are required
+// - quantity and price are optional, but cannot be negative
 func ValidateProduct(product *internal.Product) (err error) {
-	// here i must validate the product
-	// fields cannot be empty
-	if product.Name == "" || product.Quantity == 0 || product.Code_value == "" || product.Expiration == "" || product.Price == 0.0 {
-		return internal.ErrFieldsEmpty
+	verr := &internal.ValidationError{}
+
+	// required fields
+	if strings.TrimSpace(product.Name) == "" {
+		verr.Add("name", internal.RuleRequired, "name is required")
+	}
+	if strings.TrimSpace(product.Code_value) == "" {
+		verr.Add("code_value", internal.RuleRequired, "code value is required")
+	}
+	if product.Expiration.IsZero() {
+		verr.Add("expiration", internal.RuleRequired, "expiration is required")
+	}
+
+	// ranges
+	if product.Quantity < 0 {
+		verr.Add("quantity", internal.RuleRange, "quantity cannot be negative")
+	}
+	if product.Price < 0 {
+		verr.Add("price", internal.RuleRange, "price cannot be negative")
 	}
 
-	// expiration must be a valid date
-	_, err = time.Parse("02/01/2006", product.Expiration)
-	if err != nil {
-		return internal.ErrInvalidExpiration
+	return verr.Err()
+}
+
+// uniquenessError converts the repeated code error of the repository into a violation of the code value
+func uniquenessError(err error) error {
+	if !errors.Is(err, internal.ErrRepeatedCode) {
+		return err
 	}
 
-	return
+	verr := &internal.ValidationError{}
+	verr.Add("code_value", internal.RuleUnique, "code value must be unique")
+	return verr
 }
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"app/internal"
+	"cmp"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// in this file i handle the filtering, sorting and pagination of the products
+
+func (d *MovieDefault) Query(ctx context.Context, query internal.ProductQuery) (page internal.ProductPage, err error) {
+	// validate the query
+	if err = ValidateQuery(query); err != nil {
+		return
+	}
+
+	// filter
+	products := make([]internal.Product, 0)
+	for _, p := range (*d).rp.GetAll() {
+		if MatchQuery(p, query) {
+			products = append(products, p)
+		}
+	}
+
+	// sort
+	SortProducts(products, query.SortBy, query.SortDesc)
+
+	// paginate
+	page = internal.ProductPage{
+		Total:  len(products),
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}
+	start := min(query.Offset, len(products))
+	end := len(products)
+	if query.Limit > 0 {
+		end = min(start+query.Limit, len(products))
+	}
+	page.Products = products[start:end]
+
+	return
+}
+
+func (d *MovieDefault) Search(ctx context.Context, search internal.ProductSearch) (products []internal.Product, err error) {
+	// validate the search
+	if search.ExpiringWithinDays != nil && *search.ExpiringWithinDays < 0 {
+		err = fmt.Errorf("%w: expiring within days cannot be negative", internal.ErrInvalidQuery)
+		return
+	}
+
+	// candidates: ranked by relevance if there is a text, otherwise sorted by id
+	var candidates []internal.Product
+	if search.Q != "" {
+		candidates = (*d).rp.SearchByName(search.Q)
+	} else {
+		candidates = (*d).rp.GetAll()
+		SortProducts(candidates, internal.SortById, false)
+	}
+
+	// filter
+	today := time.Now().Truncate(24 * time.Hour)
+	products = make([]internal.Product, 0)
+	for _, p := range candidates {
+		if MatchSearch(p, search, today) {
+			products = append(products, p)
+		}
+	}
+
+	return
+}
+
+// MatchSearch checks if a product matches all the predicates of the search
+func MatchSearch(product internal.Product, search internal.ProductSearch, today time.Time) bool {
+	if search.PriceGt != nil && product.Price <= *search.PriceGt {
+		return false
+	}
+	if search.PriceLt != nil && product.Price >= *search.PriceLt {
+		return false
+	}
+	if search.PublishedOnly && !product.Is_published {
+		return false
+	}
+	if search.ExpiringWithinDays != nil {
+		if product.Expiration.Before(today) || product.Expiration.After(today.AddDate(0, 0, *search.ExpiringWithinDays)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateQuery checks that the query can be applied
+func ValidateQuery(query internal.ProductQuery) (err error) {
+	if query.Limit < 0 || query.Offset < 0 {
+		return fmt.Errorf("%w: limit and offset cannot be negative", internal.ErrInvalidQuery)
+	}
+
+	switch query.SortBy {
+	case "", internal.SortById, internal.SortByName, internal.SortByQuantity, internal.SortByCodeValue,
+		internal.SortByIsPublished, internal.SortByExpiration, internal.SortByPrice:
+	default:
+		return fmt.Errorf("%w: cannot sort by %s", internal.ErrInvalidQuery, query.SortBy)
+	}
+
+	return
+}
+
+// MatchQuery checks if a product matches the filters of the query
+func MatchQuery(product internal.Product, query internal.ProductQuery) bool {
+	if query.Name != "" && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(query.Name)) {
+		return false
+	}
+	if query.IsPublished != nil && product.Is_published != *query.IsPublished {
+		return false
+	}
+	if query.PriceMin != nil && product.Price < *query.PriceMin {
+		return false
+	}
+	if query.PriceMax != nil && product.Price > *query.PriceMax {
+		return false
+	}
+	if query.QuantityMin != nil && product.Quantity < *query.QuantityMin {
+		return false
+	}
+	if query.QuantityMax != nil && product.Quantity > *query.QuantityMax {
+		return false
+	}
+	if query.ExpirationBefore != nil && !product.Expiration.Before(query.ExpirationBefore.Time) {
+		return false
+	}
+	if query.ExpirationAfter != nil && !product.Expiration.After(query.ExpirationAfter.Time) {
+		return false
+	}
+	return true
+}
+
+// SortProducts sorts the products by a field, using the id to break ties
+func SortProducts(products []internal.Product, sortBy string, desc bool) {
+	sort.SliceStable(products, func(i, j int) bool {
+		a, b := products[i], products[j]
+		if desc {
+			a, b = b, a
+		}
+
+		var c int
+		switch sortBy {
+		case internal.SortByName:
+			c = strings.Compare(a.Name, b.Name)
+		case internal.SortByQuantity:
+			c = cmp.Compare(a.Quantity, b.Quantity)
+		case internal.SortByCodeValue:
+			c = strings.Compare(a.Code_value, b.Code_value)
+		case internal.SortByIsPublished:
+			c = cmp.Compare(boolToInt(a.Is_published), boolToInt(b.Is_published))
+		case internal.SortByExpiration:
+			c = a.Expiration.Compare(b.Expiration.Time)
+		case internal.SortByPrice:
+			c = cmp.Compare(a.Price, b.Price)
+		}
+		if c != 0 {
+			return c < 0
+		}
+		return a.Id < b.Id
+	})
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
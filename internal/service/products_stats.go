@@ -0,0 +1,25 @@
+package service
+
+import (
+	"app/internal"
+	"context"
+	"time"
+)
+
+func (d *MovieDefault) Stats(ctx context.Context, expiringWithinDays int) (stats internal.ProductStats) {
+	expiring := internal.ProductSearch{ExpiringWithinDays: &expiringWithinDays}
+	today := time.Now().Truncate(24 * time.Hour)
+
+	for _, p := range (*d).rp.GetAll() {
+		stats.Count++
+		stats.Stock += p.Quantity
+		stats.Value += p.Price.Mul(p.Quantity)
+		if p.Is_published {
+			stats.Published++
+		}
+		if MatchSearch(p, expiring, today) {
+			stats.Expiring++
+		}
+	}
+	return
+}
@@ -1,6 +1,15 @@
 package storage
 
-import "app/internal"
+import (
+	"app/internal"
+	"errors"
+)
+
+// errors
+var (
+	ErrFileNotFound  = errors.New("products file not found")
+	ErrFileMalformed = errors.New("products file is malformed")
+)
 
 type ProductStorageJSON interface {
 	// WriteAll is a method that writes all products in the storage
@@ -8,3 +17,18 @@ type ProductStorageJSON interface {
 	// ReadAll is a method that reads all products from the storage
 	ReadAll() (products []internal.Product, err error)
 }
+
+// ProductStorageIncremental is a storage that can persist a single change instead of all products
+type ProductStorageIncremental interface {
+	ProductStorageJSON
+	// Put is a method that creates or replaces a product in the storage
+	Put(product internal.Product) (err error)
+	// Remove is a method that deletes a product from the storage
+	Remove(id int) (err error)
+}
+
+// ProductStorageChecker is a storage that can check that it is able to persist changes
+type ProductStorageChecker interface {
+	// Check is a method that returns an error if the storage cannot be written
+	Check() (err error)
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"app/internal"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+)
+
+// operations of a journal record
+const (
+	OperationPut    = "put"
+	OperationRemove = "remove"
+)
+
+// journalRecord is a line of the journal
+// - records are idempotent, so replaying them more than once gives the same result
+type journalRecord struct {
+	Op      string            `json:"op"`
+	Id      int               `json:"id"`
+	Product *internal.Product `json:"product,omitempty"`
+}
+
+// NewStorageProductJournal creates a new instance of a storage product journal
+// - the snapshot is stored in filePath and the journal in <filePath>.journal
+// - every compactEvery records the journal is compacted into a new snapshot (0 disables it)
+func NewStorageProductJournal(filePath string, compactEvery int) *StorageProductJournal {
+	return &StorageProductJournal{
+		snapshot:     NewStorageProductJSON(filePath, false),
+		journalPath:  filePath + ".journal",
+		compactEvery: compactEvery,
+	}
+}
+
+// StorageProductJournal is a storage that appends every change to a journal file on top of a json snapshot
+type StorageProductJournal struct {
+	// mu protects the fields below
+	mu sync.Mutex
+	// snapshot is the storage of the last compacted state
+	snapshot *StorageProductJSON
+	// journalPath is the path of the journal file
+	journalPath string
+	// compactEvery is the number of records after which the journal is compacted
+	compactEvery int
+	// journal is the journal file opened for appending
+	journal *os.File
+	// records is the number of records in the journal
+	records int
+	// compactAt is the number of records at which a failed compaction is retried (0 if none failed)
+	compactAt int
+	// data is the current state (snapshot + journal)
+	data map[int]internal.Product
+}
+
+// ReadAll reads the snapshot and replays the journal on top of it
+func (s *StorageProductJournal) ReadAll() (products []internal.Product, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// snapshot
+	db, err := s.snapshot.ReadAll()
+	if err != nil {
+		return
+	}
+	s.data = make(map[int]internal.Product, len(db))
+	for _, p := range db {
+		s.data[p.Id] = p
+	}
+
+	// journal
+	if err = s.replay(); err != nil {
+		return
+	}
+
+	return s.products(), nil
+}
+
+// WriteAll writes all products as a new snapshot and empties the journal
+func (s *StorageProductJournal) WriteAll(products []internal.Product) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make(map[int]internal.Product, len(products))
+	for _, p := range products {
+		data[p.Id] = p
+	}
+
+	return s.compact(data)
+}
+
+// Put appends a record that creates or replaces the product
+func (s *StorageProductJournal) Put(product internal.Product) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = s.append(journalRecord{Op: OperationPut, Id: product.Id, Product: &product}); err != nil {
+		return
+	}
+	s.data[product.Id] = product
+
+	// the record is already durable, so a failed compaction does not fail the change
+	s.compactIfNeeded()
+	return
+}
+
+// Remove appends a record that deletes the product
+func (s *StorageProductJournal) Remove(id int) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = s.append(journalRecord{Op: OperationRemove, Id: id}); err != nil {
+		return
+	}
+	delete(s.data, id)
+
+	// the record is already durable, so a failed compaction does not fail the change
+	s.compactIfNeeded()
+	return
+}
+
+// Close closes the journal file
+func (s *StorageProductJournal) Close() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.journal == nil {
+		return
+	}
+	err = s.journal.Close()
+	s.journal = nil
+	return
+}
+
+// Check checks that the snapshot can be written
+func (s *StorageProductJournal) Check() (err error) {
+	return s.snapshot.Check()
+}
+
+// replay applies the records of the journal to data and opens it for appending
+// - a torn last record (a crash in the middle of an append) is discarded
+func (s *StorageProductJournal) replay() (err error) {
+	file, err := os.OpenFile(s.journalPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open the journal %s: %w", s.journalPath, err)
+	}
+
+	// read the records
+	var offset int64
+	s.records = 0
+	reader := bufio.NewReader(file)
+	for {
+		line, errRead := reader.ReadBytes('\n')
+		if errRead == io.EOF {
+			// a line without new line is a torn record
+			break
+		}
+		if errRead != nil {
+			file.Close()
+			return fmt.Errorf("cannot read the journal %s: %w", s.journalPath, errRead)
+		}
+
+		var record journalRecord
+		if errJSON := json.Unmarshal(line, &record); errJSON != nil {
+			file.Close()
+			return fmt.Errorf("%w: %s: record %d: %v", ErrFileMalformed, s.journalPath, s.records+1, errJSON)
+		}
+		s.apply(record)
+		s.records++
+		offset += int64(len(line))
+	}
+
+	// discard the torn record and position at the end
+	if err = file.Truncate(offset); err != nil {
+		file.Close()
+		return fmt.Errorf("cannot truncate the journal %s: %w", s.journalPath, err)
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return fmt.Errorf("cannot seek the journal %s: %w", s.journalPath, err)
+	}
+
+	if s.journal != nil {
+		s.journal.Close()
+	}
+	s.journal = file
+	return
+}
+
+// apply applies a record to data
+func (s *StorageProductJournal) apply(record journalRecord) {
+	switch record.Op {
+	case OperationPut:
+		if record.Product != nil {
+			s.data[record.Id] = *record.Product
+		}
+	case OperationRemove:
+		delete(s.data, record.Id)
+	}
+}
+
+// append writes a record at the end of the journal and syncs it
+// - if the write fails the journal is truncated back, so no partial record is left behind
+func (s *StorageProductJournal) append(record journalRecord) (err error) {
+	if s.journal == nil {
+		return fmt.Errorf("journal %s is not open", s.journalPath)
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("cannot encode the record: %w", err)
+	}
+	line = append(line, '\n')
+
+	offset, err := s.journal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("cannot seek the journal %s: %w", s.journalPath, err)
+	}
+
+	_, err = s.journal.Write(line)
+	if err == nil {
+		err = s.journal.Sync()
+	}
+	if err != nil {
+		s.journal.Truncate(offset)
+		s.journal.Seek(offset, io.SeekStart)
+		return fmt.Errorf("cannot append to the journal %s: %w", s.journalPath, err)
+	}
+
+	s.records++
+	return
+}
+
+// compactIfNeeded compacts the journal when it reaches compactEvery records
+// - a failed compaction is logged and retried once compactEvery more records are appended
+func (s *StorageProductJournal) compactIfNeeded() {
+	if s.compactEvery <= 0 || s.records < max(s.compactEvery, s.compactAt) {
+		return
+	}
+	if err := s.compact(s.data); err != nil {
+		s.compactAt = s.records + s.compactEvery
+		slog.Warn("cannot compact the journal",
+			slog.String("journal", s.journalPath),
+			slog.Int("records", s.records),
+			slog.Int("retry_at", s.compactAt),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// compact writes data as a new snapshot and empties the journal
+// - if there is a crash before the journal is emptied, replaying it again over the new snapshot is harmless
+func (s *StorageProductJournal) compact(data map[int]internal.Product) (err error) {
+	s.data = data
+	if err = s.snapshot.WriteAll(s.products()); err != nil {
+		return
+	}
+
+	if s.journal == nil {
+		s.journal, err = os.OpenFile(s.journalPath, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return fmt.Errorf("cannot open the journal %s: %w", s.journalPath, err)
+		}
+	}
+	if err = s.journal.Truncate(0); err != nil {
+		return fmt.Errorf("cannot truncate the journal %s: %w", s.journalPath, err)
+	}
+	if _, err = s.journal.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("cannot seek the journal %s: %w", s.journalPath, err)
+	}
+	s.records = 0
+	s.compactAt = 0
+	return s.journal.Sync()
+}
+
+// products returns data as a list ordered by id
+func (s *StorageProductJournal) products() (products []internal.Product) {
+	products = make([]internal.Product, 0, len(s.data))
+	for _, p := range s.data {
+		products = append(products, p)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].Id < products[j].Id })
+	return
+}
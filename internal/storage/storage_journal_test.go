@@ -0,0 +1,144 @@
+package storage_test
+
+import (
+	"app/internal"
+	"app/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageProductJournal_ReadAll(t *testing.T) {
+	t.Run("success 01 - should replay the journal on top of the snapshot", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+		err := os.WriteFile(path, []byte(`[{"id":1,"name":"product 1"},{"id":2,"name":"product 2"}]`), 0644)
+		require.NoError(t, err)
+		st := storage.NewStorageProductJournal(path, 0)
+		_, err = st.ReadAll()
+		require.NoError(t, err)
+		require.NoError(t, st.Put(internal.Product{Id: 1, Name: "product 1 updated"}))
+		require.NoError(t, st.Remove(2))
+		require.NoError(t, st.Put(internal.Product{Id: 3, Name: "product 3"}))
+		require.NoError(t, st.Close())
+
+		// act
+		st = storage.NewStorageProductJournal(path, 0)
+		products, err := st.ReadAll()
+
+		// assert
+		expectedProducts := []internal.Product{
+			{Id: 1, Name: "product 1 updated"},
+			{Id: 3, Name: "product 3"},
+		}
+		require.NoError(t, err)
+		require.Equal(t, expectedProducts, products)
+	})
+
+	t.Run("success 02 - should discard a torn last record", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+		err := os.WriteFile(path, []byte(`[]`), 0644)
+		require.NoError(t, err)
+		journal := `{"op":"put","id":1,"product":{"Id":1,"Name":"product 1"}}` + "\n" + `{"op":"put","id":2,"prod`
+		err = os.WriteFile(path+".journal", []byte(journal), 0644)
+		require.NoError(t, err)
+		st := storage.NewStorageProductJournal(path, 0)
+
+		// act
+		products, err := st.ReadAll()
+
+		// assert
+		require.NoError(t, err)
+		require.Equal(t, []internal.Product{{Id: 1, Name: "product 1"}}, products)
+		require.NoError(t, st.Put(internal.Product{Id: 2, Name: "product 2"}))
+		require.NoError(t, st.Close())
+		products, err = storage.NewStorageProductJournal(path, 0).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, []internal.Product{{Id: 1, Name: "product 1"}, {Id: 2, Name: "product 2"}}, products)
+	})
+
+	t.Run("failure 01 - corrupt record in the middle of the journal", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+		err := os.WriteFile(path, []byte(`[]`), 0644)
+		require.NoError(t, err)
+		err = os.WriteFile(path+".journal", []byte("garbage\n"), 0644)
+		require.NoError(t, err)
+		st := storage.NewStorageProductJournal(path, 0)
+
+		// act
+		_, err = st.ReadAll()
+
+		// assert
+		require.ErrorIs(t, err, storage.ErrFileMalformed)
+	})
+}
+
+func TestStorageProductJournal_Compact(t *testing.T) {
+	t.Run("success 01 - should compact the journal into the snapshot", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+		err := os.WriteFile(path, []byte(`[]`), 0644)
+		require.NoError(t, err)
+		st := storage.NewStorageProductJournal(path, 2)
+		_, err = st.ReadAll()
+		require.NoError(t, err)
+
+		// act
+		require.NoError(t, st.Put(internal.Product{Id: 1, Name: "product 1"}))
+		require.NoError(t, st.Put(internal.Product{Id: 2, Name: "product 2"}))
+
+		// assert
+		journal, err := os.ReadFile(path + ".journal")
+		require.NoError(t, err)
+		require.Empty(t, journal)
+		products, err := storage.NewStorageProductJSON(path, false).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, []internal.Product{{Id: 1, Name: "product 1"}, {Id: 2, Name: "product 2"}}, products)
+		require.NoError(t, st.Close())
+	})
+
+	t.Run("success 02 - should keep a change whose compaction fails and retry it later", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+		err := os.WriteFile(path, []byte(`[]`), 0644)
+		require.NoError(t, err)
+		st := storage.NewStorageProductJournal(path, 2)
+		_, err = st.ReadAll()
+		require.NoError(t, err)
+		// the snapshot cannot be replaced while its path is a non empty directory
+		require.NoError(t, os.Remove(path))
+		require.NoError(t, os.MkdirAll(filepath.Join(path, "dir"), 0755))
+
+		// act
+		err01 := st.Put(internal.Product{Id: 1, Name: "product 1"})
+		err02 := st.Put(internal.Product{Id: 2, Name: "product 2"})
+		require.NoError(t, os.RemoveAll(path))
+		require.NoError(t, os.WriteFile(path, []byte(`[]`), 0644))
+		err03 := st.Remove(1)
+
+		// assert
+		require.NoError(t, err01)
+		require.NoError(t, err02)
+		require.NoError(t, err03)
+		journal, err := os.ReadFile(path + ".journal")
+		require.NoError(t, err)
+		require.NotEmpty(t, journal)
+		products, err := storage.NewStorageProductJournal(path, 0).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, []internal.Product{{Id: 2, Name: "product 2"}}, products)
+
+		// the compaction is retried once compactEvery more records are appended
+		require.NoError(t, st.Put(internal.Product{Id: 3, Name: "product 3"}))
+		journal, err = os.ReadFile(path + ".journal")
+		require.NoError(t, err)
+		require.Empty(t, journal)
+		products, err = storage.NewStorageProductJSON(path, false).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, []internal.Product{{Id: 2, Name: "product 2"}, {Id: 3, Name: "product 3"}}, products)
+		require.NoError(t, st.Close())
+	})
+}
@@ -6,67 +6,156 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 )
 
-// i don´t know how to connect this with the repository
-
 type StorageProductJSON struct {
 	// filepath is the path of the file where the products are stored
 	filePath string
+	// backup indicates if the previous generation of the file is kept as <filePath>.bak
+	backup bool
 }
 
 // NewStorageProductJSON creates a new instance of a storage product json
-func NewStorageProductJSON(filePath string) *StorageProductJSON {
+func NewStorageProductJSON(filePath string, backup bool) *StorageProductJSON {
 	return &StorageProductJSON{
-		filePath: filePath}
+		filePath: filePath,
+		backup:   backup,
+	}
 }
 
 // ReadAll is a method that reads all products from the storage
 func (s *StorageProductJSON) ReadAll() (products []internal.Product, err error) {
 	// open the file
-	file, err := os.Open("/Users/martindoming/Desktop/Bootcamp/go-web/products.json")
+	file, err := os.Open(s.filePath)
 	if err != nil {
-		fmt.Println("cannot open the file: ", err)
-		return
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrFileNotFound, s.filePath)
+		}
+		return nil, fmt.Errorf("cannot open the file %s: %w", s.filePath, err)
 	}
 	defer file.Close()
 
 	// read the file
-	bytes, _ := io.ReadAll(file)
+	bytes, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the file %s: %w", s.filePath, err)
+	}
 
 	// decode the json into the struct
 	err = json.Unmarshal(bytes, &products)
 	if err != nil {
-		fmt.Println("cannot decode the json file: ", err)
-		return
+		return nil, fmt.Errorf("%w: %s: %v", ErrFileMalformed, s.filePath, err)
 	}
 
 	return
 }
 
 // WriteAll is a method that writes all products in the storage
-func (s *StorageProductJSON) WriteAll(products []internal.Product) (err error) { // i think this function must be associated with a struct but i don't know how to do it
-	// open the file
-	file, err := os.Open("/Users/martindoming/Desktop/Bootcamp/go-web/products.json")
+// - the products are written in a temporary file that replaces the original one once it is synced,
+// so a crash in the middle of the write never leaves a corrupt file
+func (s *StorageProductJSON) WriteAll(products []internal.Product) (err error) {
+	// decode the struct into a json
+	bytes, err := json.Marshal(products)
 	if err != nil {
-		fmt.Println("cannot open the file: ", err)
-		return
+		return fmt.Errorf("cannot encode the products: %w", err)
 	}
-	defer file.Close()
 
-	// decode the struct into a json
-	bytes, err := json.Marshal(products)
+	// keep the previous generation
+	if s.backup {
+		if err = backupFile(s.filePath); err != nil {
+			return
+		}
+	}
+
+	// write the file, keeping its mode
+	return writeFileAtomic(s.filePath, bytes, fileMode(s.filePath))
+}
+
+// Check is a method that checks that the directory of the file is writable, creating and removing a temporary file
+func (s *StorageProductJSON) Check() (err error) {
+	file, err := os.CreateTemp(filepath.Dir(s.filePath), "."+filepath.Base(s.filePath)+".check-*")
 	if err != nil {
-		fmt.Println("cannot encode the json file: ", err)
-		return
+		return fmt.Errorf("directory of %s is not writable: %w", s.filePath, err)
 	}
+	file.Close()
+	return os.Remove(file.Name())
+}
 
-	// write the file
-	_, err = file.Write(bytes)
+// writeFileAtomic writes data in a temporary file of the same directory and renames it over path
+// - the temporary file is created with mode 0600, so it is given the mode of the file it replaces
+func writeFileAtomic(path string, data []byte, mode os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+
+	// create the temporary file
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
 	if err != nil {
-		fmt.Println("cannot write the file: ", err)
-		return
+		return fmt.Errorf("cannot create the temporary file: %w", err)
 	}
+	// remove the temporary file if anything fails before the rename
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
 
-	return nil
+	// write and sync the content
+	if err = tmp.Chmod(mode); err != nil {
+		return fmt.Errorf("cannot set the mode of the temporary file: %w", err)
+	}
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("cannot write the temporary file: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("cannot sync the temporary file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("cannot close the temporary file: %w", err)
+	}
+
+	// replace the file
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot replace the file: %w", err)
+	}
+
+	// sync the directory so the rename is durable
+	return syncDir(dir)
+}
+
+// backupFile copies the current content of path to <path>.bak, replacing the previous backup
+// - if path does not exist yet there is nothing to back up
+func backupFile(path string) (err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("cannot read the file to back up: %w", err)
+	}
+
+	return writeFileAtomic(path+".bak", data, fileMode(path))
+}
+
+// fileMode returns the permissions of path, or 0644 if it does not exist yet
+func fileMode(path string) os.FileMode {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0644
+	}
+	return info.Mode().Perm()
+}
+
+// syncDir flushes the entries of a directory
+func syncDir(dir string) (err error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("cannot open the directory: %w", err)
+	}
+	defer d.Close()
+
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("cannot sync the directory: %w", err)
+	}
+	return
 }
@@ -0,0 +1,161 @@
+package storage_test
+
+import (
+	"app/internal"
+	"app/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageProductJSON_ReadAll(t *testing.T) {
+	t.Run("success 01 - should read the products of the file", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+		err := os.WriteFile(path, []byte(`[{"id":1,"name":"product 1","quantity":10,"code_value":"123","is_published":true,"expiration":"14/05/2024","price":100}]`), 0644)
+		require.NoError(t, err)
+		st := storage.NewStorageProductJSON(path, false)
+
+		// act
+		products, err := st.ReadAll()
+
+		// assert
+		expectedProducts := []internal.Product{
+			{Id: 1, Name: "product 1", Quantity: 10, Code_value: "123", Is_published: true, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(100, 0)},
+		}
+		require.NoError(t, err)
+		require.Equal(t, expectedProducts, products)
+	})
+
+	t.Run("failure 01 - file not found", func(t *testing.T) {
+		// arrange
+		st := storage.NewStorageProductJSON(filepath.Join(t.TempDir(), "products.json"), false)
+
+		// act
+		_, err := st.ReadAll()
+
+		// assert
+		require.ErrorIs(t, err, storage.ErrFileNotFound)
+	})
+
+	t.Run("failure 02 - file malformed", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+		err := os.WriteFile(path, []byte(`[{"id":1,`), 0644)
+		require.NoError(t, err)
+		st := storage.NewStorageProductJSON(path, false)
+
+		// act
+		_, err = st.ReadAll()
+
+		// assert
+		require.ErrorIs(t, err, storage.ErrFileMalformed)
+	})
+}
+
+func TestStorageProductJSON_WriteAll(t *testing.T) {
+	t.Run("success 01 - should replace the file and keep no temporary files", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		path := filepath.Join(dir, "products.json")
+		st := storage.NewStorageProductJSON(path, false)
+		products := []internal.Product{
+			{Id: 1, Name: "product 1", Quantity: 10, Code_value: "123", Is_published: true, Expiration: internal.NewDate(2024, 5, 14), Price: internal.NewMoney(100, 0)},
+		}
+
+		// act
+		err := st.WriteAll(products)
+
+		// assert
+		require.NoError(t, err)
+		read, err := st.ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, products, read)
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+
+	t.Run("success 02 - should keep the previous generation as backup", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+		err := os.WriteFile(path, []byte(`[]`), 0644)
+		require.NoError(t, err)
+		st := storage.NewStorageProductJSON(path, true)
+
+		// act
+		err = st.WriteAll([]internal.Product{{Id: 1, Name: "product 1"}})
+
+		// assert
+		require.NoError(t, err)
+		backup, err := os.ReadFile(path + ".bak")
+		require.NoError(t, err)
+		require.Equal(t, `[]`, string(backup))
+	})
+
+	t.Run("success 03 - should keep the mode of the file in the file and its backup", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+		err := os.WriteFile(path, []byte(`[]`), 0640)
+		require.NoError(t, err)
+		require.NoError(t, os.Chmod(path, 0640))
+		st := storage.NewStorageProductJSON(path, true)
+
+		// act
+		err = st.WriteAll([]internal.Product{{Id: 1, Name: "product 1"}})
+
+		// assert
+		require.NoError(t, err)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+		info, err = os.Stat(path + ".bak")
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	})
+
+	t.Run("success 04 - should create a new file with mode 0644", func(t *testing.T) {
+		// arrange
+		path := filepath.Join(t.TempDir(), "products.json")
+		st := storage.NewStorageProductJSON(path, false)
+
+		// act
+		err := st.WriteAll([]internal.Product{{Id: 1, Name: "product 1"}})
+
+		// assert
+		require.NoError(t, err)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0644), info.Mode().Perm())
+	})
+}
+
+func TestStorageProductJSON_Check(t *testing.T) {
+	t.Run("success 01 - writable directory, no file is left behind", func(t *testing.T) {
+		// arrange
+		dir := t.TempDir()
+		st := storage.NewStorageProductJSON(filepath.Join(dir, "products.json"), false)
+
+		// act
+		err := st.Check()
+
+		// assert
+		require.NoError(t, err)
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+
+	t.Run("failure 01 - missing directory", func(t *testing.T) {
+		// arrange
+		st := storage.NewStorageProductJSON(filepath.Join(t.TempDir(), "missing", "products.json"), false)
+
+		// act
+		err := st.Check()
+
+		// assert
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+)
+
+// errors
+var (
+	ErrProductInvalid  = errors.New("product is not valid")
+	ErrFieldOutOfRange = errors.New("field out of range")
+	ErrFieldFormat     = errors.New("field has an invalid format")
+)
+
+// rules a field can violate
+const (
+	RuleRequired = "required"
+	RuleRange    = "range"
+	RuleFormat   = "format"
+	RuleUnique   = "unique"
+)
+
+// FieldViolation is a struct that represents a rule violated by a field
+type FieldViolation struct {
+	// Field is the name of the field (as in the json body)
+	Field string `json:"field"`
+	// Rule is the rule violated
+	Rule string `json:"rule"`
+	// Message is a human readable description of the violation
+	Message string `json:"message"`
+}
+
+// ValidationError is an error with all the violations found validating a product
+// - it matches ErrProductInvalid and the sentinel error of each violation (e.g. ErrFieldsEmpty, ErrRepeatedCode)
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+// Add adds a violation
+func (e *ValidationError) Add(field, rule, message string) {
+	e.Violations = append(e.Violations, FieldViolation{Field: field, Rule: rule, Message: message})
+}
+
+// Has checks if a field has any violation
+func (e *ValidationError) Has(field string) bool {
+	for _, v := range e.Violations {
+		if v.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge adds the violations of other whose fields have no violation yet
+func (e *ValidationError) Merge(other *ValidationError) {
+	if other == nil {
+		return
+	}
+	for _, v := range other.Violations {
+		if !e.Has(v.Field) {
+			e.Violations = append(e.Violations, v)
+		}
+	}
+}
+
+// Err returns the validation error, or nil if there are no violations
+func (e *ValidationError) Err() error {
+	if e == nil || len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error returns all the violations in a single message
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return ErrProductInvalid.Error() + ": " + strings.Join(messages, "; ")
+}
+
+// Unwrap returns ErrProductInvalid and the sentinel errors of the violations
+func (e *ValidationError) Unwrap() (errs []error) {
+	errs = []error{ErrProductInvalid}
+	for _, v := range e.Violations {
+		switch v.Rule {
+		case RuleRequired:
+			errs = append(errs, ErrFieldsEmpty)
+		case RuleRange:
+			errs = append(errs, ErrFieldOutOfRange)
+		case RuleFormat:
+			errs = append(errs, ErrFieldFormat)
+			if v.Field == "expiration" {
+				errs = append(errs, ErrInvalidExpiration)
+			}
+		case RuleUnique:
+			errs = append(errs, ErrRepeatedCode)
+		}
+	}
+	return
+}
@@ -6,12 +6,22 @@ import (
 	"net/http"
 )
 
+// HeaderRequestID is the response header with the id of the request, echoed in the error bodies
+const HeaderRequestID = "X-Request-ID"
+
 type errorResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+	Errors    any    `json:"errors,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func Error(w http.ResponseWriter, statusCode int, message string) {
+	ErrorDetails(w, statusCode, message, nil)
+}
+
+// ErrorDetails writes an error response with the details of the error (e.g. a list of violations) in "errors"
+func ErrorDetails(w http.ResponseWriter, statusCode int, message string, details any) {
 	// default status code
 	defaultStatusCode := http.StatusInternalServerError
 	// check if status code is valid
@@ -21,8 +31,10 @@ func Error(w http.ResponseWriter, statusCode int, message string) {
 
 	// response
 	body := errorResponse{
-		Status:  http.StatusText(defaultStatusCode),
-		Message: message,
+		Status:    http.StatusText(defaultStatusCode),
+		Message:   message,
+		Errors:    details,
+		RequestID: w.Header().Get(HeaderRequestID),
 	}
 	bytes, err := json.Marshal(body)
 	if err != nil {
@@ -92,3 +92,26 @@ func TestErrorf(t *testing.T) {
 		require.Equal(t, expectedHeaders, rr.Header())
 	})
 }
+
+// Tests for ErrorDetails
+func TestErrorDetails(t *testing.T) {
+	t.Run("case 1: should return status code 422 with the details", func(t *testing.T) {
+		// arrange
+		// ...
+
+		// act
+		rr := httptest.NewRecorder()
+		code := http.StatusUnprocessableEntity
+		message := "error message"
+		details := []string{"detail 1", "detail 2"}
+		response.ErrorDetails(rr, code, message, details)
+
+		// assert
+		expectedCode := http.StatusUnprocessableEntity
+		expectedBody := `{"status":"Unprocessable Entity","message":"error message","errors":["detail 1","detail 2"]}`
+		expectedHeaders := http.Header{"Content-Type": []string{"application/json"}}
+		require.Equal(t, expectedCode, rr.Code)
+		require.Equal(t, expectedBody, rr.Body.String())
+		require.Equal(t, expectedHeaders, rr.Header())
+	})
+}
@@ -0,0 +1,84 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemDetails is a struct that represents an error as described in RFC 7807 (problem details for http apis)
+type ProblemDetails struct {
+	// Type is a URI that identifies the problem type (clients branch on it)
+	Type string
+	// Title is a short summary of the problem type
+	Title string
+	// Status is the http status code
+	Status int
+	// Detail is an explanation specific to this occurrence of the problem
+	Detail string
+	// Instance is a URI that identifies this occurrence of the problem
+	Instance string
+	// Extensions are additional members of the problem (e.g. the violations of a validation)
+	Extensions map[string]any
+}
+
+// MarshalJSON writes the members of the problem, omitting the empty ones, together with its extensions
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	body := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		body[k] = v
+	}
+	if p.Type != "" {
+		body["type"] = p.Type
+	}
+	if p.Title != "" {
+		body["title"] = p.Title
+	}
+	if p.Status != 0 {
+		body["status"] = p.Status
+	}
+	if p.Detail != "" {
+		body["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		body["instance"] = p.Instance
+	}
+	return json.Marshal(body)
+}
+
+// Problem writes a problem details response (application/problem+json)
+func Problem(w http.ResponseWriter, problem ProblemDetails) {
+	// default status code
+	if problem.Status < 400 || problem.Status > 599 {
+		problem.Status = http.StatusInternalServerError
+	}
+	// default type and title
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	// id of the request
+	if id := w.Header().Get(HeaderRequestID); id != "" {
+		extensions := make(map[string]any, len(problem.Extensions)+1)
+		for k, v := range problem.Extensions {
+			extensions[k] = v
+		}
+		extensions["request_id"] = id
+		problem.Extensions = extensions
+	}
+
+	bytes, err := json.Marshal(problem)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// write response
+	// - set header: before code due to it sets by default "text/plain"
+	w.Header().Set("Content-Type", "application/problem+json")
+	// - set status code
+	w.WriteHeader(problem.Status)
+	// - write body
+	w.Write(bytes)
+}
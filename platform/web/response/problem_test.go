@@ -0,0 +1,53 @@
+package response_test
+
+import (
+	"app/platform/web/response"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Tests for Problem
+func TestProblem(t *testing.T) {
+	t.Run("case 1: should write the problem with its extensions", func(t *testing.T) {
+		// arrange
+		// ...
+
+		// act
+		rr := httptest.NewRecorder()
+		problem := response.ProblemDetails{
+			Type:       "urn:problem:out-of-stock",
+			Title:      "Out of stock",
+			Status:     http.StatusConflict,
+			Detail:     "there are no units left",
+			Instance:   "/products/1",
+			Extensions: map[string]any{"quantity": 0},
+		}
+		response.Problem(rr, problem)
+
+		// assert
+		expectedCode := http.StatusConflict
+		expectedBody := `{"type":"urn:problem:out-of-stock","title":"Out of stock","status":409,"detail":"there are no units left","instance":"/products/1","quantity":0}`
+		expectedHeaders := http.Header{"Content-Type": []string{"application/problem+json"}}
+		require.Equal(t, expectedCode, rr.Code)
+		require.JSONEq(t, expectedBody, rr.Body.String())
+		require.Equal(t, expectedHeaders, rr.Header())
+	})
+
+	t.Run("case 2: should default the status, type and title", func(t *testing.T) {
+		// arrange
+		// ...
+
+		// act
+		rr := httptest.NewRecorder()
+		response.Problem(rr, response.ProblemDetails{})
+
+		// assert
+		expectedCode := http.StatusInternalServerError
+		expectedBody := `{"type":"about:blank","title":"Internal Server Error","status":500}`
+		require.Equal(t, expectedCode, rr.Code)
+		require.JSONEq(t, expectedBody, rr.Body.String())
+	})
+}